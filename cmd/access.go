@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/jack-kitto/yoink/internal/project"
+	"github.com/jack-kitto/yoink/internal/store"
+	"github.com/spf13/cobra"
+)
+
+func accessCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "access",
+		Short: "Manage which keys can decrypt each environment",
+		Long:  "Edit the per-environment creation rules in the vault's .sops.yaml and re-encrypt that environment's files so the change takes effect immediately.",
+	}
+
+	cmd.AddCommand(
+		accessGrantCmd(),
+		accessRevokeCmd(),
+	)
+
+	return cmd
+}
+
+func accessGrantCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "grant <env> <public-key>",
+		Short: "Allow an age public key or PGP fingerprint to decrypt an environment (creates a PR)",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return changeEnvAccess(args[0], strings.TrimSpace(args[1]), true)
+		},
+	}
+}
+
+func accessRevokeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "revoke <env> <public-key>",
+		Short: "Stop an age public key or PGP fingerprint from decrypting an environment (creates a PR)",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return changeEnvAccess(args[0], strings.TrimSpace(args[1]), false)
+		},
+	}
+}
+
+// changeEnvAccess adds or removes a recipient on an environment's creation
+// rule, re-encrypts the environment and opens a PR with the result
+func changeEnvAccess(env, key string, grant bool) error {
+	if err := ensureConfigLoaded(); err != nil {
+		return err
+	}
+	if err := store.ValidateEnvName(env); err != nil {
+		return err
+	}
+	if projectCfg.Encryption == store.EncryptionPassphrase {
+		return fmt.Errorf("passphrase vaults have no per-key access control")
+	}
+	if grant {
+		if err := store.ValidateRecipient(key); err != nil {
+			return err
+		}
+	}
+
+	action, done := "revoke", "revoked"
+	if grant {
+		action, done = "grant", "granted"
+	}
+
+	if dryRun {
+		fmt.Printf("🔍 [DRY RUN] Would %s access to '%s' for %s and re-encrypt its files\n", action, env, key)
+		return nil
+	}
+
+	vman, err := syncedVault()
+	if err != nil {
+		return err
+	}
+	defer vman.Cleanup()
+
+	repoDir := filepath.Join(vman.WorkDir, "repo")
+	sopsPath := filepath.Join(repoDir, ".sops.yaml")
+	before, err := project.LoadSOPSConfig(sopsPath)
+	if err != nil {
+		return fmt.Errorf("failed to read vault .sops.yaml: %w", err)
+	}
+	sopsCfg := before.Clone()
+
+	if err := editRecipients(sopsCfg.EnvRule(env), key, grant); err != nil {
+		return fmt.Errorf("%s (env '%s')", err, env)
+	}
+
+	if err := sopsCfg.Save(sopsPath); err != nil {
+		return err
+	}
+
+	count, err := reencryptChanged(repoDir, encryptor, before, sopsCfg)
+	if err != nil {
+		return fmt.Errorf("failed to re-encrypt environment '%s': %w", env, err)
+	}
+
+	msg := fmt.Sprintf("%s access to %s for %s", action, env, key)
+	if err := vman.CommitAndPush(".", msg, true); err != nil {
+		return fmt.Errorf("failed to commit and create PR: %w", err)
+	}
+
+	fmt.Printf("✅ Access %s for '%s' (%d files re-encrypted, PR created)\n", done, env, count)
+	return nil
+}
+
+// editRecipients adds or removes key from a rule, choosing the age or PGP
+// list from the key format
+func editRecipients(rule *project.CreationRule, key string, grant bool) error {
+	list := rule.KeysFor(key)
+
+	if grant {
+		if list.Contains(key) {
+			return fmt.Errorf("key already has access")
+		}
+		*list = append(*list, key)
+		return nil
+	}
+
+	if !list.Contains(key) {
+		return fmt.Errorf("key does not have access")
+	}
+	*list = list.Without(key)
+	if len(rule.Age) == 0 && len(rule.PGP) == 0 {
+		return fmt.Errorf("refusing to remove the last recipient")
+	}
+	return nil
+}
+
+// reencryptChanged re-encrypts every encrypted file in the vault checkout
+// whose creation rule recipients differ between before and after
+func reencryptChanged(repoDir string, enc store.Encryptor, before, after project.SOPSConfig) (int, error) {
+	files, err := reencryptFiles(repoDir, enc, func(rel string) bool {
+		oldRule, newRule := before.RuleFor(rel), after.RuleFor(rel)
+		return oldRule == nil || newRule == nil || !oldRule.SameKeys(*newRule)
+	})
+	return len(files), err
+}
+
+// reencryptFiles re-encrypts the encrypted files in the vault checkout
+// selected by include, rotating their data keys, and returns their
+// vault-relative paths
+func reencryptFiles(repoDir string, enc store.Encryptor, include func(rel string) bool) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(repoDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".enc.yaml") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(repoDir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if !include(rel) {
+			return nil
+		}
+
+		if !dryRun {
+			if err := store.New(path, enc).Reencrypt(); err != nil {
+				return fmt.Errorf("%s: %w", rel, err)
+			}
+		}
+		files = append(files, rel)
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jack-kitto/yoink/internal/store"
+	"github.com/spf13/cobra"
+)
+
+func applyCmd() *cobra.Command {
+	var file string
+
+	cmd := &cobra.Command{
+		Use:   "apply -f <changes.yaml>",
+		Short: "Apply a batch of set/unset operations in one PR",
+		Long: `Apply a changes file atomically: every operation is applied in a single
+load and save of the secrets file and produces one commit and one PR. If any
+operation fails, nothing is changed.
+
+  set:
+    API_KEY: abc123
+    TLS_CERT: |
+      -----BEGIN CERTIFICATE-----
+      ...
+  unset:
+    - OLD_TOKEN
+
+Use '-f -' to read the changes from stdin.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := ensureConfigLoaded(); err != nil {
+				return err
+			}
+
+			var data []byte
+			var err error
+			if file == "-" {
+				data, err = io.ReadAll(os.Stdin)
+			} else {
+				data, err = os.ReadFile(file)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read changes: %w", err)
+			}
+
+			changes, err := store.ParseChanges(data)
+			if err != nil {
+				return fmt.Errorf("invalid changes file %s: %w", file, err)
+			}
+
+			return applyChanges(changes)
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "", "Changes file (YAML with set/unset sections)")
+	cmd.MarkFlagRequired("file")
+
+	return cmd
+}
+
+// applyChanges applies a batch of changes to the secrets file in one commit
+// and PR, or lists them in dry-run mode
+func applyChanges(changes []store.Change) error {
+	if dryRun {
+		fmt.Printf("🔍 [DRY RUN] Would apply %d changes to %s:\n", len(changes), secretsFile())
+		for _, c := range changes {
+			fmt.Printf("  %s\n", c)
+		}
+		return nil
+	}
+
+	vman, err := syncedVault()
+	if err != nil {
+		return err
+	}
+
+	encPath := filepath.Join(vman.WorkDir, "repo", secretsFile())
+	if err := store.New(encPath, encryptor).Apply(changes); err != nil {
+		vman.Cleanup()
+		return err
+	}
+
+	summary := make([]string, len(changes))
+	for i, c := range changes {
+		summary[i] = c.String()
+	}
+	msg := "update secrets: " + strings.Join(summary, ", ")
+
+	if err := vman.CommitAndPush(secretsFile(), msg, true); err != nil {
+		return fmt.Errorf("failed to commit and create PR: %w", err)
+	}
+	vman.Cleanup()
+
+	fmt.Printf("✅ Applied %d changes (PR created)\n", len(changes))
+	return nil
+}
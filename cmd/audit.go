@@ -4,10 +4,9 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
-	"os/exec"
-	"strings"
 	"time"
 
+	"github.com/jack-kitto/yoink/internal/git"
 	"github.com/spf13/cobra"
 )
 
@@ -84,7 +83,11 @@ func outputAuditJSON(limit int) error {
 }
 
 func outputAuditHuman(limit int, short bool) error {
-	repoName := extractRepoName(projectCfg.VaultRepo)
+	// GitHub vaults are named owner/repo; other forges have no API here
+	repoName := projectCfg.VaultRepo
+	if _, repo, err := git.GitHubClient(projectCfg.VaultRepo); err == nil {
+		repoName = repo
+	}
 	fmt.Printf("📋 Vault Audit: %s\n", repoName)
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 
@@ -129,69 +132,51 @@ func outputAuditHuman(limit int, short bool) error {
 }
 
 func getRecentCommits(limit int) ([]CommitInfo, error) {
-	repoName := extractRepoName(projectCfg.VaultRepo)
-
-	cmd := exec.Command("gh", "api",
-		fmt.Sprintf("/repos/%s/commits", repoName),
-		"--jq",
-		fmt.Sprintf(".[:%d] | .[] | {sha: .sha, message: .commit.message, author: .commit.author.name, date: .commit.author.date}", limit))
+	client, repoName, err := git.GitHubClient(projectCfg.VaultRepo)
+	if err != nil {
+		return nil, err
+	}
 
-	output, err := cmd.Output()
+	commits, err := client.Commits(repoName, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch commits: %w", err)
 	}
 
-	var commits []CommitInfo
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-		var commit CommitInfo
-		if err := json.Unmarshal([]byte(line), &commit); err == nil {
-			commits = append(commits, commit)
-		}
+	infos := make([]CommitInfo, 0, len(commits))
+	for _, c := range commits {
+		infos = append(infos, CommitInfo{
+			SHA:     c.SHA,
+			Message: c.Commit.Message,
+			Author:  c.Commit.Author.Name,
+			Date:    c.Commit.Author.Date,
+		})
 	}
-
-	return commits, nil
+	return infos, nil
 }
 
 func getPendingPRs() ([]PRInfo, error) {
-	repoName := extractRepoName(projectCfg.VaultRepo)
-
-	cmd := exec.Command("gh", "pr", "list",
-		"--repo", repoName,
-		"--json", "number,title,author,url")
+	client, repoName, err := git.GitHubClient(projectCfg.VaultRepo)
+	if err != nil {
+		return nil, err
+	}
 
-	output, err := cmd.Output()
+	pulls, err := client.PullRequests(repoName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch PRs: %w", err)
 	}
 
-	var prs []PRInfo
-	if err := json.Unmarshal(output, &prs); err != nil {
-		return nil, fmt.Errorf("failed to parse PR JSON: %w", err)
+	prs := make([]PRInfo, 0, len(pulls))
+	for _, pr := range pulls {
+		prs = append(prs, PRInfo{
+			Number: pr.Number,
+			Title:  pr.Title,
+			Author: GitHubUser{Login: pr.User.Login},
+			URL:    pr.HTMLURL,
+		})
 	}
-
 	return prs, nil
 }
 
-func extractRepoName(gitURL string) string {
-	if strings.Contains(gitURL, "github.com:") {
-		parts := strings.Split(gitURL, ":")
-		if len(parts) >= 2 {
-			return strings.TrimSuffix(parts[1], ".git")
-		}
-	} else if strings.Contains(gitURL, "github.com/") {
-		parts := strings.Split(gitURL, "github.com/")
-		if len(parts) >= 2 {
-			return strings.TrimSuffix(parts[1], ".git")
-		}
-	}
-	return gitURL
-}
-
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
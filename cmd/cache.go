@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/jack-kitto/yoink/internal/vault"
+	"github.com/spf13/cobra"
+)
+
+func cacheCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the local encrypted copy of the vault used for offline reads",
+		Long: `yoink keeps a still-encrypted copy of the vault files it reads, keyed by
+vault commit, so get, list, export, run and render keep working when the vault
+can't be reached. Pass --offline to read from the cache without trying the
+network at all.
+
+The cache also holds a bare mirror of the vault repository. Commands that
+need a checkout clone it from the mirror after fetching only the new commits,
+instead of cloning the vault from scratch.`,
+	}
+
+	cmd.AddCommand(
+		cacheStatusCmd(),
+		cacheRefreshCmd(),
+		cacheClearCmd(),
+	)
+
+	return cmd
+}
+
+func cacheStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show which vault commits are cached and whether they are current",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := ensureConfigLoaded(); err != nil {
+				return err
+			}
+
+			cache, err := vault.OpenCache(projectCfg.VaultRepo)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("📦 Cache: %s\n", cache.Dir)
+
+			latest, updated, err := cache.Latest()
+			if err == vault.ErrNotCached {
+				fmt.Println("(vault not cached - run 'yoink cache refresh')")
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			snaps, err := cache.Snapshots()
+			if err != nil {
+				return err
+			}
+			for _, s := range snaps {
+				marker := " "
+				if s.Commit == latest {
+					marker = "*"
+				}
+				kind := "partial"
+				if s.Complete {
+					kind = "full"
+				}
+				fmt.Printf("%s %s  %-7s  %d files  %s\n", marker, vault.ShortSHA(s.Commit), kind, s.Files, s.Updated.Local().Format("2006-01-02 15:04"))
+			}
+
+			if vault.Offline {
+				fmt.Printf("ℹ️  Last updated %s ago\n", time.Since(updated).Round(time.Minute))
+				return nil
+			}
+
+			head, err := newFastStore().Commit()
+			switch {
+			case err != nil:
+				fmt.Printf("⚠️  Could not reach the vault: %v\n", err)
+			case head == latest:
+				fmt.Println("✅ Cache is up to date with the vault")
+			default:
+				fmt.Printf("⚠️  Vault has moved on to %s - run 'yoink cache refresh'\n", vault.ShortSHA(head))
+			}
+			return nil
+		},
+	}
+}
+
+func cacheRefreshCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "refresh",
+		Short: "Cache the current vault in full",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := ensureConfigLoaded(); err != nil {
+				return err
+			}
+
+			if dryRun {
+				fmt.Printf("🔍 [DRY RUN] Would cache %s\n", projectCfg.VaultRepo)
+				return nil
+			}
+
+			// Syncing caches the checkout
+			vman, err := syncedVault()
+			if err != nil {
+				return err
+			}
+			defer vman.Cleanup()
+
+			head, err := vman.Head()
+			if err != nil {
+				return err
+			}
+
+			cache, err := vault.OpenCache(projectCfg.VaultRepo)
+			if err != nil {
+				return err
+			}
+			if err := cache.Store(head, filepath.Join(vman.WorkDir, "repo")); err != nil {
+				return err
+			}
+
+			fmt.Printf("✅ Cached vault at %s\n", vault.ShortSHA(head))
+			return nil
+		},
+	}
+}
+
+func cacheClearCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "clear",
+		Short: "Remove the local copy of the vault",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := ensureConfigLoaded(); err != nil {
+				return err
+			}
+
+			cache, err := vault.OpenCache(projectCfg.VaultRepo)
+			if err != nil {
+				return err
+			}
+
+			if dryRun {
+				fmt.Printf("🔍 [DRY RUN] Would remove %s\n", cache.Dir)
+				return nil
+			}
+
+			if err := cache.Clear(); err != nil {
+				return err
+			}
+
+			fmt.Println("✅ Vault cache cleared")
+			return nil
+		},
+	}
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jack-kitto/yoink/internal/store"
+	"github.com/jack-kitto/yoink/internal/util"
+	"github.com/spf13/cobra"
+)
+
+const editHeader = `# Edit the secrets below, then save and quit to create a pull request.
+# Remove a line to delete a secret; use "|" blocks for multi-line values.
+# Quit without saving to cancel.
+`
+
+func editCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "edit",
+		Short: "Edit the decrypted secrets in $EDITOR (creates a single PR)",
+		Long:  "Decrypt the secrets file into a private temporary file, open it in $VISUAL or $EDITOR, and re-encrypt the result. All changes go into one pull request listing the added, changed and removed keys (names only). The plaintext file is overwritten and deleted afterwards.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := ensureConfigLoaded(); err != nil {
+				return err
+			}
+
+			vman, err := syncedVault()
+			if err != nil {
+				return err
+			}
+			defer vman.Cleanup()
+
+			encPath := filepath.Join(vman.WorkDir, "repo", secretsFile())
+			s := store.New(encPath, encryptor)
+			before, err := s.All()
+			if err != nil {
+				return err
+			}
+
+			after, err := editSecrets(before)
+			if err != nil {
+				return err
+			}
+
+			added, changed, removed := diffSecrets(before, after)
+			summary := summarizeChanges(added, changed, removed)
+			if summary == "" {
+				fmt.Println("ℹ️  No changes")
+				return nil
+			}
+
+			if dryRun {
+				fmt.Printf("🔍 [DRY RUN] Would update %s: %s\n", secretsFile(), summary)
+				return nil
+			}
+
+			if err := s.Replace(after); err != nil {
+				return err
+			}
+
+			if err := vman.CommitAndPush(secretsFile(), "edit secrets: "+summary, true); err != nil {
+				return fmt.Errorf("failed to commit and create PR: %w", err)
+			}
+
+			fmt.Printf("✅ Secrets updated (PR created): %s\n", summary)
+			return nil
+		},
+	}
+}
+
+// editSecrets writes the secrets to a private temporary file, opens it in
+// the user's editor until it parses, and returns the edited secrets
+func editSecrets(data map[string]string) (map[string]string, error) {
+	plaintext, err := store.EncodeYAML(data)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := util.CreatePrivateTemp("yoink-edit-*.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	path := f.Name()
+	defer util.ShredFile(path)
+
+	_, err = f.Write(append([]byte(editHeader), plaintext...))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if err := runEditor(path); err != nil {
+			return nil, err
+		}
+
+		edited, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(edited, append([]byte(editHeader), plaintext...)) {
+			return data, nil
+		}
+
+		result, err := store.DecodeYAML(edited)
+		if err == nil {
+			return result, nil
+		}
+
+		fmt.Printf("❌ Invalid secrets file: %v\n", err)
+		if !util.PromptConfirm("Re-open the editor?", true) {
+			return nil, fmt.Errorf("edit cancelled, no changes made")
+		}
+	}
+}
+
+// runEditor opens path in $VISUAL or $EDITOR, falling back to vi
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// yoink has to outlive the editor to shred the plaintext. Ctrl-C is left
+	// to the editor; any other signal that would end yoink is passed on to
+	// it and cancels the edit
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, exitSignals...)
+	defer signal.Stop(sigs)
+
+	parts := strings.Fields(editor)
+	cmd := exec.Command(parts[0], append(parts[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("editor %s failed: %w", parts[0], err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	var cancelled os.Signal
+	for {
+		select {
+		case sig := <-sigs:
+			if fromTerminal(sig) {
+				continue
+			}
+			cancelled = sig
+			signalChild(cmd, sig, false)
+		case err := <-done:
+			if cancelled != nil {
+				return fmt.Errorf("edit cancelled by %v, no changes made", cancelled)
+			}
+			if err != nil {
+				return fmt.Errorf("editor %s failed: %w", parts[0], err)
+			}
+			return nil
+		}
+	}
+}
+
+// diffSecrets returns the sorted names of keys added, changed and removed
+// between two versions of the secrets
+func diffSecrets(before, after map[string]string) (added, changed, removed []string) {
+	for k, v := range after {
+		old, exists := before[k]
+		switch {
+		case !exists:
+			added = append(added, k)
+		case old != v:
+			changed = append(changed, k)
+		}
+	}
+	for k := range before {
+		if _, exists := after[k]; !exists {
+			removed = append(removed, k)
+		}
+	}
+
+	sort.Strings(added)
+	sort.Strings(changed)
+	sort.Strings(removed)
+	return added, changed, removed
+}
+
+// summarizeChanges describes key changes by name only, or returns "" if there are none
+func summarizeChanges(added, changed, removed []string) string {
+	var parts []string
+	if len(added) > 0 {
+		parts = append(parts, "added "+strings.Join(added, ", "))
+	}
+	if len(changed) > 0 {
+		parts = append(parts, "changed "+strings.Join(changed, ", "))
+	}
+	if len(removed) > 0 {
+		parts = append(parts, "removed "+strings.Join(removed, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,322 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/jack-kitto/yoink/internal/project"
+	"github.com/jack-kitto/yoink/internal/store"
+	"github.com/jack-kitto/yoink/internal/util"
+	"github.com/jack-kitto/yoink/internal/vault"
+	"github.com/spf13/cobra"
+)
+
+func envCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "env",
+		Short: "Manage vault environments (dev, staging, prod, ...)",
+		Long:  "Manage per-environment secrets files stored under envs/<name>/secrets.enc.yaml in the vault. Select an environment for other commands with --env or default_env in .yoink.yaml.",
+	}
+
+	cmd.AddCommand(
+		envCreateCmd(),
+		envListCmd(),
+		envCopyCmd(),
+		envDeleteCmd(),
+	)
+
+	return cmd
+}
+
+func envCreateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create an empty environment (creates a PR)",
+		Long: `Create an empty environment and give it its own creation rule in the
+vault's .sops.yaml, starting from the recipients that can read it today.
+Use 'yoink access grant|revoke' to change who can decrypt it.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := ensureConfigLoaded(); err != nil {
+				return err
+			}
+			name := args[0]
+			if err := store.ValidateEnvName(name); err != nil {
+				return err
+			}
+
+			if dryRun {
+				fmt.Printf("🔍 [DRY RUN] Would create environment '%s' at %s\n", name, store.SecretsFile(name))
+				return nil
+			}
+
+			vman, err := syncedVault()
+			if err != nil {
+				return err
+			}
+			defer vman.Cleanup()
+
+			file := store.SecretsFile(name)
+			encPath := filepath.Join(vman.WorkDir, "repo", file)
+			if util.FileExists(encPath) {
+				return fmt.Errorf("environment '%s' already exists", name)
+			}
+
+			if err := addEnvRule(filepath.Join(vman.WorkDir, "repo"), name); err != nil {
+				return err
+			}
+			if err := store.New(encPath, encryptor).Replace(map[string]string{}); err != nil {
+				return err
+			}
+
+			if err := vman.CommitAndPush(".", fmt.Sprintf("create environment %s", name), true); err != nil {
+				return fmt.Errorf("failed to commit and create PR: %w", err)
+			}
+
+			fmt.Printf("✅ Environment '%s' created (PR created)\n", name)
+			return nil
+		},
+	}
+}
+
+func envListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List environments in the vault",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := ensureConfigLoaded(); err != nil {
+				return err
+			}
+
+			vman, err := syncedVault()
+			if err != nil {
+				return err
+			}
+			defer vman.Cleanup()
+
+			envs, err := listEnvs(filepath.Join(vman.WorkDir, "repo"))
+			if err != nil {
+				return err
+			}
+
+			if len(envs) == 0 {
+				fmt.Println("(no environments in vault)")
+				return nil
+			}
+			for _, e := range envs {
+				if e == envName {
+					fmt.Printf("* %s\n", e)
+				} else {
+					fmt.Printf("  %s\n", e)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func envCopyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "copy <source> <destination>",
+		Short: "Copy all secrets from one environment into a new one (creates a PR)",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := ensureConfigLoaded(); err != nil {
+				return err
+			}
+			src, dst := args[0], args[1]
+			for _, name := range []string{src, dst} {
+				if err := store.ValidateEnvName(name); err != nil {
+					return err
+				}
+			}
+
+			if dryRun {
+				fmt.Printf("🔍 [DRY RUN] Would copy environment '%s' to '%s'\n", src, dst)
+				return nil
+			}
+
+			vman, err := syncedVault()
+			if err != nil {
+				return err
+			}
+			defer vman.Cleanup()
+
+			repoDir := filepath.Join(vman.WorkDir, "repo")
+			srcPath := filepath.Join(repoDir, store.SecretsFile(src))
+			dstFile := store.SecretsFile(dst)
+			dstPath := filepath.Join(repoDir, dstFile)
+
+			if !util.FileExists(srcPath) {
+				return fmt.Errorf("environment '%s' does not exist", src)
+			}
+			if util.FileExists(dstPath) {
+				return fmt.Errorf("environment '%s' already exists", dst)
+			}
+
+			secrets, err := store.New(srcPath, encryptor).All()
+			if err != nil {
+				return err
+			}
+
+			// Re-encrypting under the destination path applies its own recipients
+			if err := addEnvRule(repoDir, dst); err != nil {
+				return err
+			}
+			if err := store.New(dstPath, encryptor).Replace(secrets); err != nil {
+				return err
+			}
+
+			if err := vman.CommitAndPush(".", fmt.Sprintf("copy environment %s to %s", src, dst), true); err != nil {
+				return fmt.Errorf("failed to commit and create PR: %w", err)
+			}
+
+			fmt.Printf("✅ Copied %d secrets from '%s' to '%s' (PR created)\n", len(secrets), src, dst)
+			return nil
+		},
+	}
+}
+
+func envDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete an environment and all of its secrets (creates a PR)",
+		Long: `Delete an environment's secrets together with its creation rule in
+.sops.yaml and its place in groups.yaml, in a single PR. A group that only
+covered the environment is removed, rather than left covering every
+environment.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := ensureConfigLoaded(); err != nil {
+				return err
+			}
+			name := args[0]
+			if err := store.ValidateEnvName(name); err != nil {
+				return err
+			}
+
+			if dryRun {
+				fmt.Printf("🔍 [DRY RUN] Would delete environment '%s'\n", name)
+				return nil
+			}
+
+			vman, err := syncedVault()
+			if err != nil {
+				return err
+			}
+			defer vman.Cleanup()
+
+			envDir := filepath.Join(store.EnvsDir, name)
+			absDir := filepath.Join(vman.WorkDir, "repo", envDir)
+			if !util.FileExists(absDir) {
+				return fmt.Errorf("environment '%s' does not exist", name)
+			}
+
+			if err := os.RemoveAll(absDir); err != nil {
+				return err
+			}
+			if err := removeEnvRule(filepath.Join(vman.WorkDir, "repo"), name); err != nil {
+				return err
+			}
+
+			if err := vman.CommitAndPush(".", fmt.Sprintf("delete environment %s", name), true); err != nil {
+				return fmt.Errorf("failed to commit and create PR: %w", err)
+			}
+
+			fmt.Printf("✅ Environment '%s' deleted (PR created)\n", name)
+			return nil
+		},
+	}
+}
+
+// syncedVault returns a vault manager with the project's vault cloned or pulled
+func syncedVault() (*vault.Manager, error) {
+	return syncVault(projectCfg.VaultRepo)
+}
+
+// syncVault clones or updates the given vault repository
+func syncVault(repoURL string) (*vault.Manager, error) {
+	vman, err := vault.New(repoURL)
+	if err != nil {
+		return nil, err
+	}
+	vman.Verbose = verbose
+
+	if err := vman.Sync(); err != nil {
+		return nil, err
+	}
+	return vman, nil
+}
+
+// addEnvRule gives a new environment its own creation rule in the vault's
+// .sops.yaml, starting from the recipients that cover it now, so access to it
+// can be granted and revoked separately. Passphrase vaults have no rules
+func addEnvRule(repoDir, env string) error {
+	if projectCfg.Encryption == store.EncryptionPassphrase {
+		return nil
+	}
+
+	sopsPath := filepath.Join(repoDir, ".sops.yaml")
+	sopsCfg, err := project.LoadSOPSConfig(sopsPath)
+	if err != nil {
+		return fmt.Errorf("failed to read vault .sops.yaml: %w", err)
+	}
+	sopsCfg.EnvRule(env)
+	return sopsCfg.Save(sopsPath)
+}
+
+// removeEnvRule drops a deleted environment's creation rule from the vault's
+// .sops.yaml and the environment from groups.yaml, so neither names
+// recipients for an environment that no longer exists
+func removeEnvRule(repoDir, env string) error {
+	if projectCfg.Encryption == store.EncryptionPassphrase {
+		return nil
+	}
+
+	sopsPath := filepath.Join(repoDir, ".sops.yaml")
+	sopsCfg, err := project.LoadSOPSConfig(sopsPath)
+	if err != nil {
+		return fmt.Errorf("failed to read vault .sops.yaml: %w", err)
+	}
+	if sopsCfg.RemoveEnvRule(env) {
+		if err := sopsCfg.Save(sopsPath); err != nil {
+			return err
+		}
+	}
+
+	groupsPath := filepath.Join(repoDir, project.GroupsFile)
+	groups, err := project.LoadGroups(groupsPath)
+	if err != nil {
+		return err
+	}
+	changed, removed := groups.RemoveEnv(env)
+	if !changed {
+		return nil
+	}
+	for _, name := range removed {
+		fmt.Printf("⚠️  Group '%s' only covered '%s' and was removed\n", name, env)
+	}
+	return groups.Save(groupsPath)
+}
+
+// listEnvs returns the sorted names of environments present in a vault checkout
+func listEnvs(repoDir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(repoDir, store.EnvsDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var envs []string
+	for _, e := range entries {
+		if e.IsDir() && util.FileExists(filepath.Join(repoDir, store.SecretsFile(e.Name()))) {
+			envs = append(envs, e.Name())
+		}
+	}
+	sort.Strings(envs)
+	return envs, nil
+}
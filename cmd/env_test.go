@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jack-kitto/yoink/internal/project"
+)
+
+func TestRemoveEnvRule(t *testing.T) {
+	const alice, bob, carol = "age1alice", "age1bob", "age1carol"
+
+	repoDir := t.TempDir()
+	sopsPath := filepath.Join(repoDir, ".sops.yaml")
+	groupsPath := filepath.Join(repoDir, project.GroupsFile)
+	projectCfg.Encryption = ""
+
+	sopsCfg := project.SOPSConfig{CreationRules: []project.CreationRule{
+		{PathRegex: project.EnvPathRegex("prod"), Age: project.KeyList{alice, bob}},
+		{PathRegex: project.EnvPathRegex("staging"), Age: project.KeyList{alice, bob, carol}},
+		{PathRegex: project.DefaultPathRegex, Age: project.KeyList{alice}},
+	}}
+	if err := sopsCfg.Save(sopsPath); err != nil {
+		t.Fatal(err)
+	}
+	groups := project.GroupsConfig{Groups: map[string]*project.Group{
+		"devs": {Envs: []string{"prod", "staging"}, Members: []project.Member{{Name: "bob", Key: bob}}},
+		"qa":   {Envs: []string{"staging"}, Members: []project.Member{{Name: "carol", Key: carol}}},
+	}}
+	if err := groups.Save(groupsPath); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := removeEnvRule(repoDir, "staging"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := project.LoadSOPSConfig(sopsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var regexes []string
+	for _, rule := range got.CreationRules {
+		regexes = append(regexes, rule.PathRegex)
+	}
+	if want := []string{project.EnvPathRegex("prod"), project.DefaultPathRegex}; !reflect.DeepEqual(regexes, want) {
+		t.Errorf("rules = %v, want %v", regexes, want)
+	}
+
+	gotGroups, err := project.LoadGroups(groupsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := gotGroups.Names(); !reflect.DeepEqual(names, []string{"devs"}) {
+		t.Errorf("groups = %v, want qa removed rather than covering every environment", names)
+	}
+	if envs := gotGroups.Groups["devs"].Envs; !reflect.DeepEqual(envs, []string{"prod"}) {
+		t.Errorf("devs envs = %v, want [prod]", envs)
+	}
+
+	// Regenerating rules from the groups must not bring the rule back
+	gotGroups.ApplyTo(&got, gotGroups.Grants(got))
+	for _, rule := range got.CreationRules {
+		if rule.PathRegex == project.EnvPathRegex("staging") {
+			t.Errorf("group update recreated the staging rule: %v", rule.Age)
+		}
+	}
+}
@@ -1,10 +1,10 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/jack-kitto/yoink/internal/store"
 	"github.com/jack-kitto/yoink/internal/vault"
@@ -14,17 +14,80 @@ import (
 func exportCmd() *cobra.Command {
 	var envFile string
 	var asJSON bool
+	var format string
+	var name, namespace string
+	var sopsEncrypt bool
+	var filter store.Filter
+
+	formats := append(append([]string{}, store.ExportFormats...), store.FormatGHA, store.FormatK8sSecret, store.FormatK8sConfigMap)
 
 	cmd := &cobra.Command{
 		Use:   "export",
-		Short: "Export decrypted secrets as .env or JSON file",
+		Short: "Export decrypted secrets as .env, shell, docker, tfvars, JSON, GitHub Actions or Kubernetes output",
+		Long: `Export decrypted secrets in sorted order, quoted for the chosen format:
+
+  dotenv         KEY=value, quoted and escaped where needed
+  posix          export KEY='value' (sh, bash, zsh)
+  fish           set -gx KEY 'value'
+  powershell     $env:KEY = 'value'
+  docker         KEY=value for docker --env-file (no multi-line values)
+  tfvars         KEY = "value" for terraform -var-file (HCL-escaped)
+  json           a JSON object
+  gha            KEY<<DELIMITER blocks appended to $GITHUB_ENV (or --env-file)
+  k8s-secret     a v1 Secret manifest (--name, --namespace)
+  k8s-configmap  a v1 ConfigMap manifest (--name, --namespace)
+
+Kubernetes manifests are labelled and annotated with the vault commit they
+were exported from.
+
+With gha the values are masked first: an ::add-mask:: workflow command is
+printed for every value (every line of multi-line values), then the blocks
+are appended to $GITHUB_ENV so later steps of the job see the secrets.
+
+Use --prefix, --strip-prefix, --include, --exclude and --map to export only
+what one service needs, e.g. --prefix API_ --strip-prefix --map
+SHARED_DB_URL=DATABASE_URL.
+
+With --sops the manifest is encrypted in memory for the
+recipients of the .sops.yaml rule matching the output file, as sops would, so
+the plaintext manifest never touches disk. Set encrypted_regex:
+^(data|stringData)$ on that rule to keep the metadata readable for
+Flux/ArgoCD.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := ensureConfigLoaded(); err != nil {
 				return err
 			}
 
+			if err := filter.Validate(); err != nil {
+				return err
+			}
+			if err := resolveAt(); err != nil {
+				return err
+			}
+			if asJSON {
+				format = store.FormatJSON
+			}
+			manifest := format == store.FormatK8sSecret || format == store.FormatK8sConfigMap
+			if sopsEncrypt && !manifest {
+				return fmt.Errorf("--sops is only supported for k8s-secret and k8s-configmap output")
+			}
+			if manifest && name == "" {
+				return fmt.Errorf("--name is required for %s output", format)
+			}
+
+			if format == store.FormatGHA {
+				if envFile == "" {
+					envFile = os.Getenv("GITHUB_ENV")
+				}
+				if envFile == "" {
+					return fmt.Errorf("GITHUB_ENV is not set - run inside GitHub Actions or pass --env-file")
+				}
+			}
+
+			var commit string
+
 			// Try fast fetch first
-			fs := store.NewFast(projectCfg.VaultRepo)
+			fs := newFastStore()
 			all, err := fs.All()
 			if err != nil && verbose {
 				fmt.Printf("⚠️  Fast fetch failed (%v), falling back to git clone...\n", err)
@@ -43,58 +106,56 @@ func exportCmd() *cobra.Command {
 				if err := vman.Sync(); err != nil {
 					return err
 				}
-
-				encPath := filepath.Join(vman.WorkDir, "repo", "secrets.enc.yaml")
-				s := store.New(encPath)
-				all, err = s.All()
-				if err != nil {
+				if err := checkoutAt(vman); err != nil {
 					return err
 				}
-			}
 
-			if asJSON {
-				data, err := json.MarshalIndent(all, "", "  ")
+				encPath := filepath.Join(vman.WorkDir, "repo", secretsFile())
+				s := store.New(encPath, encryptor)
+				all, err = s.All()
 				if err != nil {
 					return err
 				}
+				commit, _ = vman.Head()
+			}
 
-				if envFile == "" {
-					fmt.Println(string(data))
-					return nil
-				}
-
-				if dryRun {
-					fmt.Printf("🔍 [DRY RUN] Would write JSON to: %s\n", envFile)
-					return nil
-				}
+			if all, err = filter.Apply(all); err != nil {
+				return err
+			}
 
-				return os.WriteFile(envFile, data, 0o600)
+			if format == store.FormatGHA {
+				return exportGitHubEnv(all, envFile)
 			}
 
-			// Export as .env format
-			var envOutput string
-			if fs != nil {
-				envOutput, err = fs.ExportEnv()
+			var output []byte
+			if manifest {
+				if commit == "" {
+					if commit, err = fs.Commit(); err != nil && verbose {
+						fmt.Fprintf(os.Stderr, "⚠️  Could not determine vault commit: %v\n", err)
+					}
+				}
+				output, err = store.KubernetesManifest(all, format, manifestOptions(name, namespace, commit))
+				if err == nil && sopsEncrypt {
+					output, err = encryptManifest(output, envFile, name)
+				}
 			} else {
-				// This shouldn't happen given the fallback above, but just in case
-				return fmt.Errorf("no store available")
+				output, err = store.Serialize(all, format)
 			}
-
 			if err != nil {
 				return err
 			}
 
 			if envFile == "" {
-				fmt.Print(envOutput)
+				fmt.Print(string(output))
 				return nil
 			}
 
 			if dryRun {
-				fmt.Printf("🔍 [DRY RUN] Would write .env to: %s\n", envFile)
+				fmt.Printf("🔍 [DRY RUN] Would write %s output to: %s\n", format, envFile)
 				return nil
 			}
 
-			if err := os.WriteFile(envFile, []byte(envOutput), 0o600); err != nil {
+			if err := os.WriteFile(envFile, output, 0o600); err != nil {
 				return err
 			}
 
@@ -104,6 +165,80 @@ func exportCmd() *cobra.Command {
 	}
 
 	cmd.Flags().StringVar(&envFile, "env-file", "", "write output to .env file")
-	cmd.Flags().BoolVar(&asJSON, "json", false, "output in JSON format")
+	cmd.Flags().StringVar(&format, "format", store.FormatDotenv, "output format: "+strings.Join(formats, ", "))
+	cmd.Flags().BoolVar(&asJSON, "json", false, "output in JSON format (same as --format json)")
+	cmd.Flags().StringVar(&name, "name", "", "metadata.name of the Kubernetes manifest")
+	cmd.Flags().StringVar(&namespace, "namespace", "", "metadata.namespace of the Kubernetes manifest")
+	cmd.Flags().BoolVar(&sopsEncrypt, "sops", false, "SOPS-encrypt the Kubernetes manifest (for Flux/ArgoCD)")
+	atFlag(cmd)
+	filterFlags(cmd, &filter)
 	return cmd
 }
+
+// manifestOptions labels and annotates a manifest with where its secrets came from
+func manifestOptions(name, namespace, commit string) store.ManifestOptions {
+	opts := store.ManifestOptions{
+		Name:      name,
+		Namespace: namespace,
+		Labels: map[string]string{
+			"app.kubernetes.io/managed-by": "yoink",
+		},
+		Annotations: map[string]string{
+			"yoink/vault": projectCfg.VaultRepo,
+		},
+	}
+	if commit != "" {
+		opts.Labels["yoink/vault-commit"] = commit
+		opts.Annotations["yoink/vault-commit"] = commit
+	}
+	if envName != "" {
+		opts.Annotations["yoink/env"] = envName
+	}
+	return opts
+}
+
+// encryptManifest encrypts a manifest for the .sops.yaml rule matching the
+// file it will be written to, or <name>.yaml in the current directory
+func encryptManifest(manifest []byte, outPath, name string) ([]byte, error) {
+	if outPath == "" {
+		outPath = name + ".yaml"
+	}
+
+	encrypted, err := encryptor.Encrypt(manifest, outPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt manifest: %w", err)
+	}
+	return encrypted, nil
+}
+
+// exportGitHubEnv masks the secrets in the job log and appends them to a
+// $GITHUB_ENV file
+func exportGitHubEnv(all map[string]string, envFile string) error {
+	env, masks, err := store.GitHubEnv(all)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		fmt.Printf("🔍 [DRY RUN] Would append %d secrets to: %s\n", len(all), envFile)
+		return nil
+	}
+
+	// Mask before the values can show up anywhere else
+	fmt.Print(string(masks))
+
+	f, err := os.OpenFile(envFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(env); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ %d secrets added to %s\n", len(all), envFile)
+	return nil
+}
@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"github.com/jack-kitto/yoink/internal/store"
+	"github.com/spf13/cobra"
+)
+
+// filterFlags registers the secret filter flags shared by export, run and render
+func filterFlags(cmd *cobra.Command, f *store.Filter) {
+	cmd.Flags().StringVar(&f.Prefix, "prefix", "", "only use secrets whose names start with this prefix")
+	cmd.Flags().BoolVar(&f.StripPrefix, "strip-prefix", false, "remove --prefix from the secret names")
+	cmd.Flags().StringSliceVar(&f.Include, "include", nil, "only use secrets matching these glob patterns, e.g. 'DB_*'")
+	cmd.Flags().StringSliceVar(&f.Exclude, "exclude", nil, "leave out secrets matching these glob patterns")
+	cmd.Flags().StringToStringVar(&f.Map, "map", nil, "pass secret SRC on as DEST (SRC=DEST, repeatable)")
+}
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/jack-kitto/yoink/internal/project"
+	"github.com/jack-kitto/yoink/internal/store"
+	"github.com/spf13/cobra"
+)
+
+func groupCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "group",
+		Short: "Manage named groups of recipients",
+		Long:  "Manage teams of recipients in the vault's groups.yaml. The vault's .sops.yaml is regenerated from group membership and affected files are re-encrypted.",
+	}
+
+	cmd.AddCommand(
+		groupCreateCmd(),
+		groupAddCmd(),
+		groupRemoveCmd(),
+		groupListCmd(),
+	)
+
+	return cmd
+}
+
+func groupCreateCmd() *cobra.Command {
+	var envs []string
+
+	cmd := &cobra.Command{
+		Use:   "create <group>",
+		Short: "Create a group (creates a PR)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			for _, e := range envs {
+				if err := store.ValidateEnvName(e); err != nil {
+					return err
+				}
+			}
+
+			scope := "all environments"
+			if len(envs) > 0 {
+				scope = strings.Join(envs, ", ")
+			}
+
+			return updateGroups(fmt.Sprintf("create group %s (%s)", name, scope), func(groups *project.GroupsConfig) error {
+				if _, exists := groups.Groups[name]; exists {
+					return fmt.Errorf("group '%s' already exists", name)
+				}
+				groups.Groups[name] = &project.Group{Envs: envs, Members: []project.Member{}}
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&envs, "envs", nil, "Environments the group can decrypt (default: all)")
+
+	return cmd
+}
+
+func groupAddCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "add <group> <member> <public-key>",
+		Short: "Add a named member's age public key or PGP fingerprint to a group (creates a PR)",
+		Args:  cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, member, key := args[0], args[1], strings.TrimSpace(args[2])
+			if err := store.ValidateRecipient(key); err != nil {
+				return err
+			}
+
+			return updateGroups(fmt.Sprintf("add %s to group %s", member, name), func(groups *project.GroupsConfig) error {
+				g, ok := groups.Groups[name]
+				if !ok {
+					return fmt.Errorf("group '%s' does not exist - run 'yoink group create %s' first", name, name)
+				}
+				if g.Member(member) >= 0 {
+					return fmt.Errorf("'%s' is already a member of group '%s'", member, name)
+				}
+				g.Members = append(g.Members, project.Member{Name: member, Key: key})
+				return nil
+			})
+		},
+	}
+}
+
+func groupRemoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove <group> <member>",
+		Short: "Remove a member from a group and re-encrypt without their key (creates a PR)",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, member := args[0], args[1]
+
+			err := updateGroups(fmt.Sprintf("remove %s from group %s", member, name), func(groups *project.GroupsConfig) error {
+				g, ok := groups.Groups[name]
+				if !ok {
+					return fmt.Errorf("group '%s' does not exist", name)
+				}
+				idx := g.Member(member)
+				if idx < 0 {
+					return fmt.Errorf("'%s' is not a member of group '%s'", member, name)
+				}
+				g.Members = append(g.Members[:idx], g.Members[idx+1:]...)
+				return nil
+			})
+			if err == nil && !dryRun {
+				fmt.Printf("⚠️  If %s was also granted access to the group's environments with 'yoink access grant', that access went too - grant it again if it's still needed\n", member)
+			}
+			return err
+		},
+	}
+}
+
+func groupListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List groups and their members",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := ensureConfigLoaded(); err != nil {
+				return err
+			}
+
+			vman, err := syncedVault()
+			if err != nil {
+				return err
+			}
+			defer vman.Cleanup()
+
+			groups, err := project.LoadGroups(filepath.Join(vman.WorkDir, "repo", project.GroupsFile))
+			if err != nil {
+				return err
+			}
+
+			if len(groups.Groups) == 0 {
+				fmt.Println("(no groups in vault)")
+				return nil
+			}
+
+			for _, name := range groups.Names() {
+				g := groups.Groups[name]
+				scope := "all environments"
+				if len(g.Envs) > 0 {
+					scope = strings.Join(g.Envs, ", ")
+				}
+				fmt.Printf("👥 %s (%s)\n", name, scope)
+				if len(g.Members) == 0 {
+					fmt.Println("   (no members)")
+				}
+				for _, m := range g.Members {
+					fmt.Printf("   • %s  %s\n", m.Name, m.Key)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// updateGroups applies a change to the vault's groups, regenerates
+// .sops.yaml from the new membership, re-encrypts affected files and opens a PR
+func updateGroups(msg string, mutate func(groups *project.GroupsConfig) error) error {
+	if err := ensureConfigLoaded(); err != nil {
+		return err
+	}
+	if projectCfg.Encryption == store.EncryptionPassphrase {
+		return fmt.Errorf("passphrase vaults have no per-key access control")
+	}
+
+	if dryRun {
+		fmt.Printf("🔍 [DRY RUN] Would %s and re-encrypt affected files\n", msg)
+		return nil
+	}
+
+	vman, err := syncedVault()
+	if err != nil {
+		return err
+	}
+	defer vman.Cleanup()
+
+	repoDir := filepath.Join(vman.WorkDir, "repo")
+	groupsPath := filepath.Join(repoDir, project.GroupsFile)
+	sopsPath := filepath.Join(repoDir, ".sops.yaml")
+
+	groups, err := project.LoadGroups(groupsPath)
+	if err != nil {
+		return err
+	}
+	before, err := project.LoadSOPSConfig(sopsPath)
+	if err != nil {
+		return fmt.Errorf("failed to read vault .sops.yaml: %w", err)
+	}
+
+	previous := groups.Grants(before)
+	if err := mutate(&groups); err != nil {
+		return err
+	}
+
+	sopsCfg := before.Clone()
+	groups.ApplyTo(&sopsCfg, previous)
+	for _, rule := range sopsCfg.CreationRules {
+		if len(rule.Age) == 0 && len(rule.PGP) == 0 {
+			return fmt.Errorf("refusing to leave rule %s without recipients", rule.PathRegex)
+		}
+	}
+
+	if err := groups.Save(groupsPath); err != nil {
+		return err
+	}
+	if err := sopsCfg.Save(sopsPath); err != nil {
+		return err
+	}
+
+	count, err := reencryptChanged(repoDir, encryptor, before, sopsCfg)
+	if err != nil {
+		return fmt.Errorf("failed to re-encrypt vault: %w", err)
+	}
+
+	if err := vman.CommitAndPush(".", msg, true); err != nil {
+		return fmt.Errorf("failed to commit and create PR: %w", err)
+	}
+
+	fmt.Printf("✅ Groups updated: %s (%d files re-encrypted, PR created)\n", msg, count)
+	return nil
+}
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/jack-kitto/yoink/internal/store"
+	"github.com/jack-kitto/yoink/internal/vault"
+	"github.com/spf13/cobra"
+)
+
+// atRevision is the --at flag of read commands; readRef is the branch, tag or
+// commit it resolves to, which the fast store and vault clones read from
+var (
+	atRevision string
+	readRef    string
+)
+
+// atDateLayouts are the date forms --at accepts, in local time unless a zone
+// is given
+var atDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+func atFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&atRevision, "at", "", "Read the vault as of a commit, tag, branch or date (2024-05-01, 2024-05-01T15:04)")
+}
+
+// resolveAt turns --at into the ref reads use. Commits, tags and branches are
+// used as they are; a date becomes the last commit on the vault's default
+// branch at or before it, a bare date meaning the start of that day. A tag
+// or branch named like a date, such as 2024-05-01, wins over the date
+func resolveAt() error {
+	readRef = atRevision
+	when, ok := parseAtDate(atRevision)
+	if !ok || isVaultRef(atRevision) {
+		return nil
+	}
+	if vault.Offline {
+		return fmt.Errorf("--at with a date needs the vault's history - pass a commit or tag to read offline")
+	}
+
+	commit, err := commitBefore(when)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "📅 Reading the vault as of %s (commit %s)\n", when.Format("2006-01-02 15:04"), vault.ShortSHA(commit))
+	readRef = commit
+	return nil
+}
+
+// isVaultRef reports whether the vault has a branch or tag named ref, asking
+// the cache of refs read before when offline
+func isVaultRef(ref string) bool {
+	if vault.Offline {
+		cache, err := vault.OpenCache(projectCfg.VaultRepo)
+		if err != nil {
+			return false
+		}
+		_, err = cache.Ref(ref)
+		return err == nil
+	}
+	_, err := vault.RemoteHead(projectCfg.VaultRepo, ref)
+	return err == nil
+}
+
+func parseAtDate(s string) (time.Time, bool) {
+	for _, layout := range atDateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// commitBefore finds the commit the vault's default branch was at on t. It
+// follows the branch's first parents in a clone, so commits of a pull request
+// merged after t don't count even when they were written before it; the
+// GitHub API can't list commits that way
+func commitBefore(t time.Time) (string, error) {
+	vman, err := syncedVault()
+	if err != nil {
+		return "", err
+	}
+	defer vman.Cleanup()
+	return vman.CommitBefore(t)
+}
+
+// checkoutAt moves a vault clone to the revision selected with --at
+func checkoutAt(vman *vault.Manager) error {
+	if readRef == "" {
+		return nil
+	}
+	return vman.Checkout(readRef)
+}
+
+func historyCmd() *cobra.Command {
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "history <key>",
+		Short: "List the vault commits that added, changed or removed a secret",
+		Long: `List the commits of the vault's default branch that added, changed or
+removed a secret, newest first, with their author and date.
+
+yoink encrypts every value with a fresh data key whenever it writes the file,
+so a secret's stored ciphertext changes in every commit, not only in those
+that changed the secret. Instead, each revision of the file is decrypted with
+your key and the secret's values are compared; values are never printed.
+This means history needs read access to the environment, decrypts once per
+commit that touched the file (a gpg call each with pgp encryption), and stops
+at the first revision your key can't decrypt, such as one from before you
+were granted access.
+
+Read an old value with 'yoink get KEY --at <commit>'.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := ensureConfigLoaded(); err != nil {
+				return err
+			}
+			key := args[0]
+
+			vman, err := syncedVault()
+			if err != nil {
+				return err
+			}
+			defer vman.Cleanup()
+
+			file := secretsFile()
+			revs, err := vman.FileHistory(file)
+			if err != nil {
+				return err
+			}
+
+			changes, complete := keyChanges(vman, revs, file, key)
+			if len(changes) == 0 && complete {
+				return fmt.Errorf("secret '%s' has never been in the vault", key)
+			}
+
+			fmt.Printf("📜 History of %s\n", key)
+			fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+			for i, c := range changes {
+				if limit > 0 && i == limit {
+					break
+				}
+				fmt.Printf("%s  %s  %-8s  %s  (%s)\n",
+					vault.ShortSHA(c.Commit), c.Date.Local().Format("2006-01-02 15:04"), c.change, c.Author, c.Subject)
+			}
+			if !complete {
+				fmt.Println("⚠️  Older revisions can't be decrypted with your key, so earlier changes aren't shown")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&limit, "limit", 0, "Show at most this many changes")
+
+	return cmd
+}
+
+// keyChange is a revision in which a secret was added, changed or removed
+type keyChange struct {
+	vault.Revision
+	change string
+}
+
+// keyChanges compares a secret across the revisions of a file, newest first.
+// It stops at the first revision the user's key can't decrypt, reporting
+// the history as incomplete
+func keyChanges(vman *vault.Manager, revs []vault.Revision, file, key string) ([]keyChange, bool) {
+	type state struct {
+		value   string
+		present bool
+	}
+	states := make([]state, 0, len(revs))
+
+	complete := true
+	for _, rev := range revs {
+		data, err := vman.FileAt(rev.Commit, file)
+		if errors.Is(err, fs.ErrNotExist) {
+			states = append(states, state{})
+			continue
+		}
+		var secrets map[string]string
+		if err == nil {
+			secrets, err = store.DecryptSecrets(data, encryptor)
+		}
+		if err != nil {
+			if verbose {
+				fmt.Printf("⚠️  Could not read %s at %s: %v\n", file, vault.ShortSHA(rev.Commit), err)
+			}
+			complete = false
+			break
+		}
+		value, ok := secrets[key]
+		states = append(states, state{value: value, present: ok})
+	}
+
+	var changes []keyChange
+	for i, s := range states {
+		// The state before this revision; nothing before the oldest one
+		var prev state
+		if i+1 < len(states) {
+			prev = states[i+1]
+		} else if !complete {
+			continue
+		}
+
+		switch {
+		case s.present && !prev.present:
+			changes = append(changes, keyChange{revs[i], "added"})
+		case !s.present && prev.present:
+			changes = append(changes, keyChange{revs[i], "removed"})
+		case s.present && s.value != prev.value:
+			changes = append(changes, keyChange{revs[i], "changed"})
+		}
+	}
+	return changes, complete
+}
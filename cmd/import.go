@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/jack-kitto/yoink/internal/store"
+	"github.com/spf13/cobra"
+)
+
+func importCmd() *cobra.Command {
+	var format, prefix string
+	var overwrite, skipExisting bool
+
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import secrets from a .env, JSON, YAML or SOPS file (creates a PR)",
+		Long: `Import secrets from an existing file into the vault in a single PR.
+
+Formats: dotenv (including 'export K=...' shell files, quoted and multi-line
+values), json, yaml, and sops (SOPS-encrypted YAML, JSON or dotenv files,
+decrypted in memory with your key). The format is detected from the file when
+--format is not given. Use '-' to read from stdin.
+
+A key-level diff against the vault is shown first. Keys that already exist
+with a different value stop the import unless --overwrite or --skip-existing
+is given.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := ensureConfigLoaded(); err != nil {
+				return err
+			}
+			if overwrite && skipExisting {
+				return fmt.Errorf("--overwrite and --skip-existing can't be used together")
+			}
+
+			path := args[0]
+			var data []byte
+			var err error
+			if path == "-" {
+				data, err = io.ReadAll(os.Stdin)
+			} else {
+				data, err = os.ReadFile(path)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", path, err)
+			}
+
+			if format == "" {
+				format = store.DetectFormat(path, data)
+			}
+			incoming, err := store.ParseImport(data, format, encryptor)
+			if err != nil {
+				return fmt.Errorf("failed to parse %s as %s: %w", path, format, err)
+			}
+			if len(incoming) == 0 {
+				return fmt.Errorf("no secrets found in %s", path)
+			}
+			for k := range incoming {
+				if err := store.ValidateKey(prefix + k); err != nil {
+					return fmt.Errorf("can't import %s: %w", k, err)
+				}
+			}
+
+			vman, err := syncedVault()
+			if err != nil {
+				return err
+			}
+			defer vman.Cleanup()
+
+			encPath := filepath.Join(vman.WorkDir, "repo", secretsFile())
+			s := store.New(encPath, encryptor)
+			current, err := s.All()
+			if err != nil {
+				return err
+			}
+
+			keys := make([]string, 0, len(incoming))
+			for k := range incoming {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			fmt.Printf("📥 Importing %d secrets from %s (%s):\n", len(keys), path, format)
+
+			var changes []store.Change
+			var conflicts int
+			for _, k := range keys {
+				key, value := prefix+k, incoming[k]
+				existing, exists := current[key]
+				switch {
+				case !exists:
+					fmt.Printf("  + %s\n", key)
+					changes = append(changes, store.Change{Key: key, Value: value})
+				case existing == value:
+					fmt.Printf("  = %s (unchanged)\n", key)
+				case overwrite:
+					fmt.Printf("  ~ %s (overwritten)\n", key)
+					changes = append(changes, store.Change{Key: key, Value: value})
+				case skipExisting:
+					fmt.Printf("  - %s (exists, skipped)\n", key)
+				default:
+					fmt.Printf("  ! %s (exists with a different value)\n", key)
+					conflicts++
+				}
+			}
+
+			if conflicts > 0 {
+				return fmt.Errorf("%d keys already exist with different values - use --overwrite or --skip-existing", conflicts)
+			}
+			if len(changes) == 0 {
+				fmt.Println("ℹ️  Vault already up to date")
+				return nil
+			}
+
+			if dryRun {
+				fmt.Printf("🔍 [DRY RUN] Would import %d secrets into %s\n", len(changes), secretsFile())
+				return nil
+			}
+
+			if err := s.Apply(changes); err != nil {
+				return err
+			}
+
+			msg := fmt.Sprintf("import %d secrets from %s", len(changes), filepath.Base(path))
+			if err := vman.CommitAndPush(secretsFile(), msg, true); err != nil {
+				return fmt.Errorf("failed to commit and create PR: %w", err)
+			}
+
+			fmt.Printf("✅ Imported %d secrets (PR created)\n", len(changes))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "", "Source format: dotenv, json, yaml or sops (default: detect)")
+	cmd.Flags().StringVar(&prefix, "prefix", "", "Prefix to add to every imported key")
+	cmd.Flags().BoolVar(&overwrite, "overwrite", false, "Replace existing secrets with the imported values")
+	cmd.Flags().BoolVar(&skipExisting, "skip-existing", false, "Keep existing secrets and import only new keys")
+
+	return cmd
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/jack-kitto/yoink/internal/config"
+	"github.com/jack-kitto/yoink/internal/github"
 	"github.com/jack-kitto/yoink/internal/util"
 	"github.com/spf13/cobra"
 )
@@ -40,7 +41,8 @@ func keySyncSetupCmd() *cobra.Command {
 			}
 
 			// Get current GitHub username
-			username, err := getCurrentGitHubUser()
+			client := github.New("", "")
+			username, err := client.CurrentUser()
 			if err != nil {
 				return fmt.Errorf("failed to get GitHub username: %w", err)
 			}
@@ -50,29 +52,23 @@ func keySyncSetupCmd() *cobra.Command {
 			fmt.Printf("🔧 Setting up key backup repository: %s\n", repoName)
 
 			// Check if repo already exists
-			checkCmd := exec.Command("gh", "repo", "view", repoName)
-			if err := checkCmd.Run(); err == nil {
+			exists, err := client.RepoExists(repoName)
+			if err != nil {
+				return fmt.Errorf("failed to check repository: %w", err)
+			}
+			if exists {
 				fmt.Println("✅ Repository already exists")
 				return nil
 			}
 
 			// Create private repository
-			createCmd := exec.Command("gh", "repo", "create", repoName,
-				"--private",
-				"--description", "Yoink Age key backup (private)",
-				"--clone=false")
-
-			if verbose {
-				createCmd.Stdout = os.Stdout
-				createCmd.Stderr = os.Stderr
-			}
-
-			if err := createCmd.Run(); err != nil {
+			repo, err := client.CreateRepo(repoName, "Yoink Age key backup (private)", true)
+			if err != nil {
 				return fmt.Errorf("failed to create repository: %w", err)
 			}
 
 			fmt.Println("✅ Private key backup repository created")
-			fmt.Printf("🔐 Repository: https://github.com/%s\n", repoName)
+			fmt.Printf("🔐 Repository: %s\n", repo.HTMLURL)
 			fmt.Println("💡 Use 'yoink key-sync push' to backup your current key")
 
 			return nil
@@ -102,38 +98,9 @@ func keySyncPushCmd() *cobra.Command {
 				return fmt.Errorf("Age key not found at %s - run 'yoink init' first", keyPath)
 			}
 
-			// Get username and repo info
-			username, err := getCurrentGitHubUser()
-			if err != nil {
-				return err
-			}
-
-			repoName := fmt.Sprintf("%s/yoink-keys", username)
-
-			fmt.Printf("🔐 Backing up Age key to %s...\n", repoName)
-
-			// Create temporary workspace
-			tmpDir, err := os.MkdirTemp("", "yoink-key-backup-*")
-			if err != nil {
+			if err := pushKeyBackup(keyPath, message); err != nil {
 				return err
 			}
-			defer os.RemoveAll(tmpDir)
-
-			// Clone the backup repo
-			cloneCmd := exec.Command("gh", "repo", "clone", repoName, tmpDir)
-			if !verbose {
-				cloneCmd.Stdout = nil
-				cloneCmd.Stderr = nil
-			}
-
-			if err := cloneCmd.Run(); err != nil {
-				return fmt.Errorf("failed to clone backup repository: %w", err)
-			}
-
-			// Copy and encrypt the key
-			if err := backupKeyToRepo(keyPath, tmpDir, message); err != nil {
-				return fmt.Errorf("failed to backup key: %w", err)
-			}
 
 			fmt.Println("✅ Age key backed up successfully")
 			fmt.Println("🔒 Key is encrypted with a passphrase derived from your GitHub username")
@@ -170,7 +137,8 @@ func keySyncPullCmd() *cobra.Command {
 			}
 
 			// Get username and repo info
-			username, err := getCurrentGitHubUser()
+			client := github.New("", "")
+			username, err := client.CurrentUser()
 			if err != nil {
 				return err
 			}
@@ -187,14 +155,8 @@ func keySyncPullCmd() *cobra.Command {
 			defer os.RemoveAll(tmpDir)
 
 			// Clone the backup repo
-			cloneCmd := exec.Command("gh", "repo", "clone", repoName, tmpDir)
-			if !verbose {
-				cloneCmd.Stdout = nil
-				cloneCmd.Stderr = nil
-			}
-
-			if err := cloneCmd.Run(); err != nil {
-				return fmt.Errorf("failed to clone backup repository: %w", err)
+			if err := cloneKeyBackup(client, repoName, tmpDir); err != nil {
+				return err
 			}
 
 			// Restore the key
@@ -220,7 +182,8 @@ func keySyncStatusCmd() *cobra.Command {
 		Short: "Check key sync repository status",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Get username
-			username, err := getCurrentGitHubUser()
+			client := github.New("", "")
+			username, err := client.CurrentUser()
 			if err != nil {
 				return err
 			}
@@ -232,8 +195,11 @@ func keySyncStatusCmd() *cobra.Command {
 			fmt.Printf("Repository: %s\n", repoName)
 
 			// Check if repo exists
-			checkCmd := exec.Command("gh", "repo", "view", repoName)
-			if err := checkCmd.Run(); err != nil {
+			exists, err := client.RepoExists(repoName)
+			if err != nil {
+				return fmt.Errorf("failed to check repository: %w", err)
+			}
+			if !exists {
 				fmt.Println("❌ Backup repository not found")
 				fmt.Println("   Run 'yoink key-sync setup' to create it")
 				return nil
@@ -250,7 +216,7 @@ func keySyncStatusCmd() *cobra.Command {
 			}
 
 			// Get last backup info
-			if err := showLastBackup(repoName); err != nil {
+			if err := showLastBackup(client, repoName); err != nil {
 				fmt.Printf("⚠️  Could not fetch backup history: %v\n", err)
 			}
 
@@ -261,7 +227,72 @@ func keySyncStatusCmd() *cobra.Command {
 
 // Helper functions for key backup/restore operations
 
-func backupKeyToRepo(keyPath, repoDir, message string) error {
+// keyBackupRepo returns the user's key backup repository and whether it exists
+func keyBackupRepo() (string, bool, error) {
+	client := github.New("", "")
+	username, err := client.CurrentUser()
+	if err != nil {
+		return "", false, err
+	}
+
+	repoName := fmt.Sprintf("%s/yoink-keys", username)
+	exists, err := client.RepoExists(repoName)
+	return repoName, exists, err
+}
+
+// pushKeyBackup clones the key backup repository and pushes keyPath to it
+func pushKeyBackup(keyPath, message string) error {
+	client := github.New("", "")
+	username, err := client.CurrentUser()
+	if err != nil {
+		return err
+	}
+
+	repoName := fmt.Sprintf("%s/yoink-keys", username)
+
+	fmt.Printf("🔐 Backing up Age key to %s...\n", repoName)
+
+	// Create temporary workspace
+	tmpDir, err := os.MkdirTemp("", "yoink-key-backup-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Clone the backup repo
+	if err := cloneKeyBackup(client, repoName, tmpDir); err != nil {
+		return err
+	}
+
+	// Copy and encrypt the key
+	if err := backupKeyToRepo(keyPath, tmpDir, message, client.GitEnv()); err != nil {
+		return fmt.Errorf("failed to backup key: %w", err)
+	}
+	return nil
+}
+
+// cloneKeyBackup clones the key backup repository over HTTPS, authenticated
+// with the client's token
+func cloneKeyBackup(client *github.Client, repoName, dir string) error {
+	repo, err := client.Repo(repoName)
+	if err != nil {
+		return fmt.Errorf("failed to find backup repository: %w", err)
+	}
+
+	cloneCmd := exec.Command("git", "clone", repo.CloneURL, dir)
+	cloneCmd.Env = append(os.Environ(), client.GitEnv()...)
+	if verbose {
+		cloneCmd.Stdout = os.Stdout
+		cloneCmd.Stderr = os.Stderr
+	}
+
+	if err := cloneCmd.Run(); err != nil {
+		return fmt.Errorf("failed to clone backup repository: %w", err)
+	}
+	return nil
+}
+
+func backupKeyToRepo(keyPath, repoDir, message string, gitEnv []string) error {
 	// Read the Age key
 	keyData, err := os.ReadFile(keyPath)
 	if err != nil {
@@ -301,6 +332,7 @@ func backupKeyToRepo(keyPath, repoDir, message string) error {
 
 	for _, cmdArgs := range commands {
 		cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+		cmd.Env = append(os.Environ(), gitEnv...)
 		if !verbose {
 			cmd.Stdout = nil
 			cmd.Stderr = nil
@@ -345,17 +377,19 @@ func restoreKeyFromRepo(repoDir, keyPath string) error {
 	return nil
 }
 
-func showLastBackup(repoName string) error {
-	cmd := exec.Command("gh", "api",
-		fmt.Sprintf("/repos/%s/commits", repoName),
-		"--jq", ".[0] | {message: .commit.message, date: .commit.author.date, author: .commit.author.name}")
-
-	output, err := cmd.Output()
+func showLastBackup(client *github.Client, repoName string) error {
+	commits, err := client.Commits(repoName, 1)
 	if err != nil {
 		return err
 	}
+	if len(commits) == 0 {
+		fmt.Println("📅 No backups yet")
+		return nil
+	}
 
-	fmt.Printf("📅 Last backup: %s\n", strings.TrimSpace(string(output)))
+	last := commits[0].Commit
+	fmt.Printf("📅 Last backup: %s (%s, by %s)\n",
+		strings.TrimSpace(last.Message), last.Author.Date.Local().Format("2006-01-02 15:04"), last.Author.Name)
 	return nil
 }
 
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/jack-kitto/yoink/internal/store"
+	"github.com/spf13/cobra"
+)
+
+func rekeyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rekey",
+		Short: "Re-encrypt every vault file for the recipients in .sops.yaml (creates a PR)",
+		Long:  "Rewrite the recipients of every encrypted file in the vault to match the current .sops.yaml and rotate each file's data key, so keys removed from .sops.yaml can no longer decrypt the latest secrets.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := ensureConfigLoaded(); err != nil {
+				return err
+			}
+
+			vman, err := syncedVault()
+			if err != nil {
+				return err
+			}
+			defer vman.Cleanup()
+
+			repoDir := filepath.Join(vman.WorkDir, "repo")
+			if projectCfg.Encryption != store.EncryptionPassphrase {
+				if err := store.CheckSOPSConfig(repoDir); err != nil {
+					return err
+				}
+			}
+
+			files, err := reencryptFiles(repoDir, encryptor, func(string) bool { return true })
+			if err != nil {
+				return fmt.Errorf("failed to rekey vault: %w", err)
+			}
+
+			if dryRun {
+				fmt.Printf("🔍 [DRY RUN] Would rekey %d files:\n", len(files))
+				for _, f := range files {
+					fmt.Printf("  %s\n", f)
+				}
+				return nil
+			}
+
+			if len(files) == 0 {
+				fmt.Println("ℹ️  No encrypted files in vault")
+				return nil
+			}
+
+			if err := vman.CommitAndPush(".", "rekey vault", true); err != nil {
+				return fmt.Errorf("failed to commit and create PR: %w", err)
+			}
+
+			fmt.Printf("✅ Rekeyed %d files (PR created)\n", len(files))
+			return nil
+		},
+	}
+}
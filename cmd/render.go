@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/jack-kitto/yoink/internal/store"
+	"github.com/jack-kitto/yoink/internal/util"
+	"github.com/jack-kitto/yoink/internal/vault"
+	"github.com/spf13/cobra"
+)
+
+func renderCmd() *cobra.Command {
+	var output string
+	var strict bool
+	var filter store.Filter
+
+	cmd := &cobra.Command{
+		Use:   "render <template>",
+		Short: "Render a config file from a Go template filled with secrets",
+		Long: `Render a Go text/template with secrets from the vault. Besides the
+standard template functions these are available:
+
+  secret "KEY"              the value of KEY in the selected environment
+  secretEnv "prod" "KEY"    the value of KEY in another environment
+  b64enc                    base64-encode a value
+  quote                     double-quote and escape a value as a JSON
+                            string, which YAML reads the same way
+
+  password: {{ secret "DB_PASSWORD" | quote }}
+
+--prefix, --strip-prefix, --include, --exclude and --map limit and rename
+the secrets templates can see, in every environment.
+
+Missing secrets render as empty strings with a warning; with --strict they
+fail the render. Output written with -o is created 0600 and added to
+.gitignore.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := ensureConfigLoaded(); err != nil {
+				return err
+			}
+
+			if err := filter.Validate(); err != nil {
+				return err
+			}
+
+			text, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to read template: %w", err)
+			}
+
+			src := &secretSource{strict: strict, filter: filter}
+			defer src.Close()
+
+			tmpl, err := template.New(filepath.Base(args[0])).Funcs(src.funcs()).Parse(string(text))
+			if err != nil {
+				return fmt.Errorf("invalid template: %w", err)
+			}
+
+			// Render fully before writing so a failure never leaves a
+			// half-written file behind
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, nil); err != nil {
+				return fmt.Errorf("failed to render %s: %w", args[0], err)
+			}
+			for _, k := range src.missingKeys() {
+				fmt.Fprintf(os.Stderr, "⚠️  Secret %s not found, rendered as empty\n", k)
+			}
+
+			if output == "" {
+				fmt.Print(buf.String())
+				return nil
+			}
+
+			if dryRun {
+				fmt.Printf("🔍 [DRY RUN] Would render %s to: %s\n", args[0], output)
+				return nil
+			}
+
+			if err := writePrivateFile(output, buf.Bytes()); err != nil {
+				return err
+			}
+			ignoreOutput(output)
+
+			fmt.Printf("✅ Rendered %s to %s\n", args[0], output)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "write the rendered file here instead of stdout")
+	cmd.Flags().BoolVar(&strict, "strict", false, "fail if a template refers to a secret that doesn't exist")
+	filterFlags(cmd, &filter)
+	return cmd
+}
+
+// secretSource loads secrets for templates on first use, one environment at
+// a time, through the fast store with a vault clone as fallback
+type secretSource struct {
+	strict  bool
+	filter  store.Filter
+	envs    map[string]map[string]string
+	missing map[string]bool
+	vman    *vault.Manager
+}
+
+func (s *secretSource) funcs() template.FuncMap {
+	return template.FuncMap{
+		"secret": func(key string) (string, error) {
+			return s.lookup(envName, key)
+		},
+		"secretEnv": func(env, key string) (string, error) {
+			if err := store.ValidateEnvName(env); err != nil {
+				return "", err
+			}
+			return s.lookup(env, key)
+		},
+		"b64enc": func(v string) string {
+			return base64.StdEncoding.EncodeToString([]byte(v))
+		},
+		"quote": quoteJSON,
+	}
+}
+
+// quoteJSON quotes a value as a JSON string. Unlike strconv.Quote it never
+// produces Go-only escapes such as \a or \x00, so the result is valid in
+// both JSON and YAML; HTML characters are left as they are
+func quoteJSON(v string) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// lookup returns a secret from an environment, recording it as missing (or
+// failing, in strict mode) when it doesn't exist
+func (s *secretSource) lookup(env, key string) (string, error) {
+	all, err := s.all(env)
+	if err != nil {
+		return "", err
+	}
+
+	val, ok := all[key]
+	if ok {
+		return val, nil
+	}
+
+	name := key
+	if env != envName {
+		name = env + "/" + key
+	}
+	if s.strict {
+		return "", fmt.Errorf("secret %s not found", name)
+	}
+	if s.missing == nil {
+		s.missing = make(map[string]bool)
+	}
+	s.missing[name] = true
+	return "", nil
+}
+
+func (s *secretSource) all(env string) (map[string]string, error) {
+	if all, ok := s.envs[env]; ok {
+		return all, nil
+	}
+
+	fs := newFastStore()
+	fs.File = store.SecretsFile(env)
+	all, err := fs.All()
+	if err == nil && env != "" && !fs.Found() {
+		return nil, fmt.Errorf("environment %s not found in vault", env)
+	}
+	if err != nil {
+		if verbose {
+			fmt.Printf("⚠️  Fast fetch failed (%v), falling back to git clone...\n", err)
+		}
+		if s.vman == nil {
+			if s.vman, err = syncedVault(); err != nil {
+				return nil, err
+			}
+		}
+
+		encPath := filepath.Join(s.vman.WorkDir, "repo", store.SecretsFile(env))
+		if env != "" && !util.FileExists(encPath) {
+			return nil, fmt.Errorf("environment %s not found in vault", env)
+		}
+		if all, err = store.New(encPath, encryptor).All(); err != nil {
+			return nil, err
+		}
+	}
+
+	if all, err = s.filter.Apply(all); err != nil {
+		return nil, err
+	}
+
+	if s.envs == nil {
+		s.envs = make(map[string]map[string]string)
+	}
+	s.envs[env] = all
+	return all, nil
+}
+
+func (s *secretSource) missingKeys() []string {
+	keys := make([]string, 0, len(s.missing))
+	for k := range s.missing {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Close removes the vault clone, if one was needed
+func (s *secretSource) Close() {
+	if s.vman != nil {
+		s.vman.Cleanup()
+	}
+}
+
+// writePrivateFile writes data to path readable only by the owner, tightening
+// the permissions of an existing file before anything is written to it
+func writePrivateFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := f.Chmod(0o600); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ignoreOutput adds a rendered file inside the current directory to .gitignore
+func ignoreOutput(path string) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	rel, err := filepath.Rel(wd, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return
+	}
+	if err := util.WriteGitignore([]string{"/" + filepath.ToSlash(rel)}); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not add %s to .gitignore: %v\n", rel, err)
+	}
+}
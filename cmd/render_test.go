@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jack-kitto/yoink/internal/vault"
+)
+
+type plainEncryptor struct{}
+
+func (plainEncryptor) Encrypt(plaintext []byte, path string) ([]byte, error) { return plaintext, nil }
+func (plainEncryptor) Decrypt(ciphertext []byte) ([]byte, error)             { return ciphertext, nil }
+
+func TestRenderMissingEnvironment(t *testing.T) {
+	const commit = "1111111111111111111111111111111111111111"
+	const vaultRepo = "https://github.com/o/vault.git"
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	projectCfg.VaultRepo, encryptor = vaultRepo, plainEncryptor{}
+	vault.Offline = true
+	t.Cleanup(func() { vault.Offline = false })
+
+	// The cache holds a complete copy of a vault with only a prod environment
+	repo := t.TempDir()
+	for file, content := range map[string]string{
+		"secrets.enc.yaml":           "ENV: dev\n",
+		"envs/prod/secrets.enc.yaml": "ENV: prod\n",
+	} {
+		path := filepath.Join(repo, filepath.FromSlash(file))
+		os.MkdirAll(filepath.Dir(path), 0o700)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cache, err := vault.OpenCache(vaultRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Store(commit, repo); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.SetLatest(commit); err != nil {
+		t.Fatal(err)
+	}
+
+	src := &secretSource{}
+	defer src.Close()
+	if v, err := src.lookup("prod", "ENV"); err != nil || v != "prod" {
+		t.Errorf("prod ENV = %q, %v", v, err)
+	}
+	_, err = src.lookup("staging", "ENV")
+	if want := "environment staging not found in vault"; err == nil || err.Error() != want {
+		t.Errorf("staging err = %v, want %q", err, want)
+	}
+}
@@ -1,15 +1,17 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/jack-kitto/yoink/internal/config"
+	"github.com/jack-kitto/yoink/internal/git"
 	"github.com/jack-kitto/yoink/internal/project"
 	"github.com/jack-kitto/yoink/internal/store"
 	"github.com/jack-kitto/yoink/internal/util"
@@ -20,18 +22,24 @@ var (
 	cfg          config.Config
 	projectCfg   project.ProjectConfig
 	secretStore  *store.Store
+	encryptor    store.Encryptor
 	configLoaded bool
 	projectMode  bool
 	dryRun       bool
 	verbose      bool
 	version      string
 	autoPR       bool
+	envName      string
 )
 
 func Execute(v string) {
 	version = v
 	root := buildRoot()
 	if err := root.Execute(); err != nil {
+		var status exitStatus
+		if errors.As(err, &status) {
+			os.Exit(int(status))
+		}
 		fmt.Println(err)
 		os.Exit(1)
 	}
@@ -47,6 +55,8 @@ func buildRoot() *cobra.Command {
 
 	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Show what would be done without making changes")
 	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "Show detailed output including git operations")
+	rootCmd.PersistentFlags().BoolVar(&vault.Offline, "offline", false, "Read secrets from the local vault cache without contacting the vault")
+	rootCmd.PersistentFlags().StringVarP(&envName, "env", "e", "", "Environment to operate on (defaults to default_env in .yoink.yaml)")
 
 	rootCmd.AddCommand(
 		initCmd(),
@@ -54,18 +64,29 @@ func buildRoot() *cobra.Command {
 		resetCmd(),
 		vaultResetCmd(),
 		setCmd(),
+		editCmd(),
+		applyCmd(),
+		importCmd(),
 		getCmd(),
 		debugCmd(),
 		deleteCmd(),
 		listCmd(),
 		exportCmd(),
+		renderCmd(),
+		cacheCmd(),
 		runCmd(),
 		onboardCmd(),
 		removeUserCmd(),
 		versionCmd(),
 		statusCmd(),
 		auditCmd(),
+		historyCmd(),
 		keySyncCmd(),
+		envCmd(),
+		accessCmd(),
+		groupCmd(),
+		rekeyCmd(),
+		rotateCmd(),
 	)
 
 	return rootCmd
@@ -73,7 +94,9 @@ func buildRoot() *cobra.Command {
 
 // Update commands to use fast store for read operations
 func getCmd() *cobra.Command {
-	return &cobra.Command{
+	var previous bool
+
+	cmd := &cobra.Command{
 		Use:   "get <key>",
 		Short: "Retrieve a secret's decrypted value (secure temporary operation)",
 		Args:  cobra.ExactArgs(1),
@@ -81,10 +104,19 @@ func getCmd() *cobra.Command {
 			if err := ensureConfigLoaded(); err != nil {
 				return err
 			}
+			if err := resolveAt(); err != nil {
+				return err
+			}
 
 			// Try fast fetch first
-			fs := store.NewFast(projectCfg.VaultRepo)
-			val, err := fs.Get(args[0])
+			fs := newFastStore()
+			var val string
+			var err error
+			if previous {
+				val, err = fs.Previous(args[0])
+			} else {
+				val, err = fs.Get(args[0])
+			}
 			if err == nil {
 				fmt.Printf("%s=%s\n", args[0], val)
 				return nil
@@ -106,10 +138,17 @@ func getCmd() *cobra.Command {
 			if err := vman.Sync(); err != nil {
 				return err
 			}
+			if err := checkoutAt(vman); err != nil {
+				return err
+			}
 
-			encPath := filepath.Join(vman.WorkDir, "repo", "secrets.enc.yaml")
-			s := store.New(encPath)
-			val, err = s.Get(args[0])
+			encPath := filepath.Join(vman.WorkDir, "repo", secretsFile())
+			s := store.New(encPath, encryptor)
+			if previous {
+				val, err = s.Previous(args[0])
+			} else {
+				val, err = s.Get(args[0])
+			}
 			if err != nil {
 				return err
 			}
@@ -117,6 +156,13 @@ func getCmd() *cobra.Command {
 			return nil
 		},
 	}
+
+	cmd.Flags().BoolVar(&previous, "previous", false, "Show the value a rotated secret had before its last rotation")
+	atFlag(cmd)
+	cmd.Flags().StringVar(&atRevision, "ref", "", "Same as --at")
+	cmd.MarkFlagsMutuallyExclusive("at", "ref")
+
+	return cmd
 }
 
 // ensureConfigLoaded loads and prepares vault if possible.
@@ -129,22 +175,101 @@ func ensureConfigLoaded() error {
 	if err != nil {
 		return fmt.Errorf("run 'yoink vault-init' first in your project: %w", err)
 	}
+
+	enc, err := store.NewEncryptor(projCfg.Encryption)
+	if err != nil {
+		return err
+	}
+
+	if envName == "" {
+		envName = projCfg.DefaultEnv
+	}
+	if envName != "" {
+		if err := store.ValidateEnvName(envName); err != nil {
+			return err
+		}
+	}
+
+	applyForgeConfig(projCfg)
+	if _, err := git.ForgeFor(projCfg.VaultRepo); err != nil {
+		return err
+	}
+
 	projectCfg = projCfg
+	encryptor = enc
 	configLoaded = true
 	projectMode = true
 	return nil
 }
 
+// applyForgeConfig registers the forge and forge_url set in .yoink.yaml for
+// the project's vault, which ForgeFor and GitHubClient then use instead of
+// detecting the forge from the vault URL
+func applyForgeConfig(cfg project.ProjectConfig) {
+	if cfg.Forge != "" || cfg.ForgeURL != "" {
+		git.SetForgeConfig(cfg.VaultRepo, git.ForgeConfig{Kind: cfg.Forge, URL: cfg.ForgeURL})
+	}
+}
+
+// secretsFile returns the vault-relative secrets file for the selected environment
+func secretsFile() string {
+	return store.SecretsFile(envName)
+}
+
+// newFastStore creates a fast store for the selected environment, backed by
+// the local vault cache
+func newFastStore() *store.FastStore {
+	fs := store.NewFast(projectCfg.VaultRepo, encryptor)
+	fs.File = secretsFile()
+	fs.Ref = readRef
+	if cache, err := vault.OpenCache(projectCfg.VaultRepo); err == nil {
+		fs.Cache = cache
+	}
+	return fs
+}
+
 func setCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:   "set <key> <value>",
+	var fromFile string
+
+	cmd := &cobra.Command{
+		Use:   "set <key> [value|-] | set KEY=VALUE...",
 		Short: "Store or update a secret (creates a PR to vault automatically)",
-		Args:  cobra.ExactArgs(2),
+		Long: `Store or update a secret. To keep the value out of shell history and ps
+output, pass '-' to read it from stdin, --from-file to read it from a file
+(multi-line values such as PEM certificates are kept as-is), or leave it out
+to be prompted without echo.
+
+Several secrets can be set at once with KEY=VALUE arguments; they are applied
+together in a single PR.`,
+		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := ensureConfigLoaded(); err != nil {
 				return err
 			}
-			key, val := args[0], args[1]
+
+			if strings.Contains(args[0], "=") {
+				if fromFile != "" {
+					return fmt.Errorf("--from-file can't be combined with KEY=VALUE arguments")
+				}
+				changes, err := store.ParseAssignments(args)
+				if err != nil {
+					return err
+				}
+				return applyChanges(changes)
+			}
+			if len(args) > 2 {
+				return fmt.Errorf("too many arguments - use KEY=VALUE to set several secrets at once")
+			}
+
+			key := args[0]
+			if err := store.ValidateKey(key); err != nil {
+				return err
+			}
+			val, err := readSecretValue(key, args[1:], fromFile)
+			if err != nil {
+				return err
+			}
+
 			vman, err := vault.New(projectCfg.VaultRepo)
 			if err != nil {
 				return err
@@ -163,13 +288,13 @@ func setCmd() *cobra.Command {
 				return err
 			}
 
-			encPath := filepath.Join(vman.WorkDir, "repo", "secrets.enc.yaml")
-			s := store.New(encPath)
+			encPath := filepath.Join(vman.WorkDir, "repo", secretsFile())
+			s := store.New(encPath, encryptor)
 			if err := s.Set(key, val); err != nil {
 				return err
 			}
 
-			if err := vman.CommitAndPush("secrets.enc.yaml", fmt.Sprintf("update secret %s", key), true); err != nil {
+			if err := vman.CommitAndPush(secretsFile(), fmt.Sprintf("update secret %s", key), true); err != nil {
 				return fmt.Errorf("failed to commit and create PR: %w", err)
 			}
 
@@ -177,25 +302,71 @@ func setCmd() *cobra.Command {
 			return nil
 		},
 	}
+
+	cmd.Flags().StringVar(&fromFile, "from-file", "", "Read the value from a file")
+
+	return cmd
+}
+
+// readSecretValue resolves a secret's value from the command line, stdin
+// ("-"), a file, or a hidden prompt when none is given
+func readSecretValue(key string, args []string, fromFile string) (string, error) {
+	if fromFile != "" {
+		if len(args) > 0 {
+			return "", fmt.Errorf("pass either a value or --from-file, not both")
+		}
+		data, err := os.ReadFile(fromFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", fromFile, err)
+		}
+		return string(data), nil
+	}
+
+	if len(args) > 0 && args[0] == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read value from stdin: %w", err)
+		}
+		// Drop the newline echo and here-strings add
+		value := strings.TrimSuffix(string(data), "\n")
+		return strings.TrimSuffix(value, "\r"), nil
+	}
+
+	if len(args) > 0 {
+		return args[0], nil
+	}
+
+	value, err := util.PromptHidden(fmt.Sprintf("🔑 Value for %s: ", key))
+	if err != nil {
+		return "", fmt.Errorf("no value given - pass a value, '-' to read stdin, or --from-file: %w", err)
+	}
+	return value, nil
 }
 
 func deleteCmd() *cobra.Command {
 	return &cobra.Command{
-		Use:   "delete <key>",
-		Short: "Delete a secret entry (creates a PR to remove it)",
-		Args:  cobra.ExactArgs(1),
+		Use:   "delete <key>...",
+		Short: "Delete one or more secrets (creates a PR to remove them)",
+		Args:  cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := ensureConfigLoaded(); err != nil {
 				return err
 			}
+			if len(args) > 1 {
+				changes := make([]store.Change, len(args))
+				for i, key := range args {
+					changes[i] = store.Change{Key: key, Delete: true}
+				}
+				return applyChanges(changes)
+			}
 			key := args[0]
 			vman, _ := vault.New(projectCfg.VaultRepo)
 			_ = vman.Sync()
-			s := store.New(filepath.Join(vman.WorkDir, "repo", "secrets.enc.yaml"))
+			s := store.New(filepath.Join(vman.WorkDir, "repo", secretsFile()), encryptor)
 			if err := s.Delete(key); err != nil {
 				return err
 			}
-			if err := vman.CommitAndPush("secrets.enc.yaml", fmt.Sprintf("delete secret %s", key), true); err != nil {
+			if err := vman.CommitAndPush(secretsFile(), fmt.Sprintf("delete secret %s", key), true); err != nil {
 				return err
 			}
 			vman.Cleanup()
@@ -290,16 +461,19 @@ func versionCmd() *cobra.Command {
 }
 
 func listCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List all secret keys from the remote vault",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := ensureConfigLoaded(); err != nil {
 				return err
 			}
+			if err := resolveAt(); err != nil {
+				return err
+			}
 
 			// Try fast fetch first
-			fs := store.NewFast(projectCfg.VaultRepo)
+			fs := newFastStore()
 			keys, err := fs.Keys()
 			if err == nil {
 				if len(keys) == 0 {
@@ -328,9 +502,12 @@ func listCmd() *cobra.Command {
 			if err := vman.Sync(); err != nil {
 				return err
 			}
+			if err := checkoutAt(vman); err != nil {
+				return err
+			}
 
-			encPath := filepath.Join(vman.WorkDir, "repo", "secrets.enc.yaml")
-			s := store.New(encPath)
+			encPath := filepath.Join(vman.WorkDir, "repo", secretsFile())
+			s := store.New(encPath, encryptor)
 			keys, err = s.Keys()
 			if err != nil {
 				return err
@@ -345,81 +522,8 @@ func listCmd() *cobra.Command {
 			return nil
 		},
 	}
-}
-
-func runCmd() *cobra.Command {
-	cmd := &cobra.Command{
-		Use:   "run -- <command>",
-		Short: "Run a command with secrets injected as environment variables",
-		Args:  cobra.MinimumNArgs(1),
-		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := ensureConfigLoaded(); err != nil {
-				return err
-			}
-
-			// Try fast fetch first
-			fs := store.NewFast(projectCfg.VaultRepo)
-			envMap, err := fs.All()
-			if err != nil && verbose {
-				fmt.Printf("⚠️  Fast fetch failed (%v), falling back to git clone...\n", err)
-			}
-
-			// Fallback to traditional method if fast fetch fails
-			if err != nil {
-				vman, err := vault.New(projectCfg.VaultRepo)
-				if err != nil {
-					return fmt.Errorf("failed to initialize vault: %w", err)
-				}
-				vman.Verbose = verbose
-
-				defer vman.Cleanup()
-
-				if err := vman.Sync(); err != nil {
-					return fmt.Errorf("failed to sync vault: %w", err)
-				}
-
-				encPath := filepath.Join(vman.WorkDir, "repo", "secrets.enc.yaml")
-				s := store.New(encPath)
-				envMap, err = s.All()
-				if err != nil {
-					return fmt.Errorf("failed to load secrets: %w", err)
-				}
-			}
-
-			if dryRun {
-				fmt.Println("🔍 [DRY RUN] Would run command with injected secrets:")
-				for k := range envMap {
-					fmt.Printf("  %s=***\n", k)
-				}
-				fmt.Printf("Command: %v\n", args)
-				return nil
-			}
-
-			// Start with current environment
-			env := os.Environ()
-
-			// Add secrets as environment variables
-			for k, v := range envMap {
-				env = append(env, fmt.Sprintf("%s=%s", k, v))
-			}
-
-			// Execute the command with inherited streams
-			c := exec.Command(args[0], args[1:]...)
-			c.Env = env
-			c.Stdout = os.Stdout
-			c.Stderr = os.Stderr
-			c.Stdin = os.Stdin
-
-			if !verbose {
-				fmt.Printf("🚀 Running command with %d secrets...\n", len(envMap))
-			} else {
-				fmt.Printf("🚀 Running command with %d injected secrets: %v\n", len(envMap), args)
-			}
-
-			return c.Run()
-		},
-	}
 
+	atFlag(cmd)
 	return cmd
 }
 
@@ -457,7 +561,7 @@ func debugCmd() *cobra.Command {
 				return nil
 			})
 
-			encPath := filepath.Join(repoDir, "secrets.enc.yaml")
+			encPath := filepath.Join(repoDir, secretsFile())
 			if util.FileExists(encPath) {
 				fmt.Printf("Secrets file exists: %s\n", encPath)
 
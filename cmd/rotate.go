@@ -0,0 +1,477 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/jack-kitto/yoink/internal/config"
+	"github.com/jack-kitto/yoink/internal/project"
+	"github.com/jack-kitto/yoink/internal/store"
+	"github.com/jack-kitto/yoink/internal/util"
+	"github.com/spf13/cobra"
+)
+
+func rotateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "Rotate keys and secrets",
+	}
+
+	cmd.AddCommand(
+		rotateKeyCmd(),
+		rotateSecretCmd(),
+	)
+
+	return cmd
+}
+
+func rotateKeyCmd() *cobra.Command {
+	var vaults []string
+	var abort bool
+
+	cmd := &cobra.Command{
+		Use:   "key",
+		Short: "Replace your age key in every vault you have access to",
+		Long: `Generate a new age identity and add its public key next to the old one in
+every vault yoink knows about - the current project's, those set up with
+'yoink vault-init' and any passed with --vault - rekeying each vault in a PR.
+Once those PRs are merged, run 'yoink rotate key' again to remove the old key
+from each vault, install the new key and update your key-sync backup.
+
+Progress is saved in ~/.config/yoink/rotation.yaml, so an interrupted
+rotation resumes where it stopped. If the PRs adding the new key won't be
+merged, 'yoink rotate key --abort' drops the rotation and the new key and
+keeps using the current one; it refuses once the old key has been removed
+from a vault.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rot, err := config.LoadKeyRotation()
+			if err != nil {
+				return fmt.Errorf("failed to read rotation progress: %w", err)
+			}
+
+			if abort {
+				return abortKeyRotation(rot)
+			}
+
+			if rot == nil {
+				targets := knownVaults(vaults)
+				if len(targets) == 0 {
+					return fmt.Errorf("no known vaults - run from a project directory or pass --vault")
+				}
+
+				if dryRun {
+					fmt.Println("🔍 [DRY RUN] Would generate a new age key and rotate it into:")
+					for _, url := range targets {
+						fmt.Printf("  %s\n", url)
+					}
+					return nil
+				}
+
+				if rot, err = startKeyRotation(targets); err != nil {
+					return err
+				}
+			} else {
+				fmt.Printf("🔄 Resuming key rotation started %s\n", rot.StartedAt)
+				for _, url := range vaults {
+					if _, ok := rot.Vaults[url]; !ok {
+						rot.Vaults[url] = config.RotationPending
+					}
+				}
+
+				if dryRun {
+					fmt.Printf("🔍 [DRY RUN] Rotation from %s to %s:\n", rot.OldPublicKey, rot.NewPublicKey)
+					for _, url := range sortedVaults(rot) {
+						fmt.Printf("  %s (%s)\n", url, rot.Vaults[url])
+					}
+					return nil
+				}
+			}
+
+			waiting := 0
+			for _, url := range sortedVaults(rot) {
+				status := rot.Vaults[url]
+				if status == config.RotationDone || status == config.RotationSkipped {
+					continue
+				}
+
+				next, err := rotateVaultKey(url, rot)
+				if err != nil {
+					return fmt.Errorf("failed to rotate key in %s: %w", url, err)
+				}
+
+				switch {
+				case next == config.RotationSkipped:
+					fmt.Printf("⏭️  %s: old key is not a recipient, skipping\n", url)
+				case next == config.RotationAdded && status == config.RotationPending:
+					fmt.Printf("📤 %s: new key added and vault rekeyed (PR created)\n", url)
+				case next == config.RotationAdded:
+					fmt.Printf("⏳ %s: waiting for the PR adding your new key to be merged\n", url)
+				case next == config.RotationDone:
+					fmt.Printf("📤 %s: old key removed and vault rekeyed (PR created)\n", url)
+				}
+				if next == config.RotationAdded {
+					waiting++
+				}
+
+				rot.Vaults[url] = next
+				if err := rot.Save(); err != nil {
+					return fmt.Errorf("failed to save rotation progress: %w", err)
+				}
+			}
+
+			if waiting > 0 {
+				fmt.Printf("💡 Merge the PRs for %d vault(s), then run 'yoink rotate key' again to finish\n", waiting)
+				return nil
+			}
+
+			return finishKeyRotation(rot)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&vaults, "vault", nil, "Additional vault repositories to rotate (default: every vault yoink has used)")
+	cmd.Flags().BoolVar(&abort, "abort", false, "Abandon the rotation in progress and keep the current key")
+
+	return cmd
+}
+
+func rotateSecretCmd() *cobra.Command {
+	var generator string
+	var grace time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "secret <key>",
+		Short: "Replace a secret with a generated value (creates a PR)",
+		Long: `Generate a new value for a secret and store it, keeping the previous value in
+the secret's history for a grace period so consumers can be switched over.
+Read the previous value with 'yoink get <key> --previous'.
+
+Generators: random:N (N alphanumeric characters), hex:N (N hex characters),
+uuid, password.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := ensureConfigLoaded(); err != nil {
+				return err
+			}
+			key := args[0]
+			if err := store.ValidateKey(key); err != nil {
+				return err
+			}
+
+			value, err := util.GenerateSecret(generator)
+			if err != nil {
+				return err
+			}
+
+			if dryRun {
+				fmt.Printf("🔍 [DRY RUN] Would rotate %s with a %s value, keeping the previous value for %s\n", key, generator, grace)
+				return nil
+			}
+
+			vman, err := syncedVault()
+			if err != nil {
+				return err
+			}
+
+			encPath := filepath.Join(vman.WorkDir, "repo", secretsFile())
+			if err := store.New(encPath, encryptor).Rotate(key, value, grace); err != nil {
+				vman.Cleanup()
+				return err
+			}
+
+			if err := vman.CommitAndPush(secretsFile(), fmt.Sprintf("rotate secret %s", key), true); err != nil {
+				return fmt.Errorf("failed to commit and create PR: %w", err)
+			}
+			vman.Cleanup()
+
+			fmt.Printf("✅ Secret '%s' rotated (PR created)\n", key)
+			fmt.Printf("🕒 Previous value kept until %s\n", time.Now().Add(grace).Format(time.RFC3339))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&generator, "generator", "random:32", "Value generator: random:N, hex:N, uuid or password")
+	cmd.Flags().DurationVar(&grace, "grace", 7*24*time.Hour, "How long to keep the previous value")
+
+	return cmd
+}
+
+// knownVaults returns the vaults recorded in the global config, the current
+// project's vault and any extra ones, sorted and without duplicates
+func knownVaults(extra []string) []string {
+	seen := map[string]bool{}
+	add := func(url string) {
+		if url != "" {
+			seen[url] = true
+		}
+	}
+
+	if cfg, err := config.LoadConfig(); err == nil {
+		for _, url := range cfg.Vaults {
+			add(url)
+		}
+	}
+	if projCfg, err := project.LoadProject(); err == nil {
+		add(projCfg.VaultRepo)
+	}
+	for _, url := range extra {
+		add(url)
+	}
+
+	urls := make([]string, 0, len(seen))
+	for url := range seen {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+func sortedVaults(rot *config.KeyRotation) []string {
+	urls := make([]string, 0, len(rot.Vaults))
+	for url := range rot.Vaults {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// startKeyRotation generates the replacement key and records the vaults to rotate
+func startKeyRotation(targets []string) (*config.KeyRotation, error) {
+	keyPath, err := config.GetAgeKeyPath()
+	if err != nil {
+		return nil, err
+	}
+	oldPublicKey, err := store.AgePublicKey(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("no age key to rotate: %w", err)
+	}
+
+	newKeyPath, err := config.GetNewAgeKeyPath()
+	if err != nil {
+		return nil, err
+	}
+	newPublicKey, err := store.GenerateAgeKey(newKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate age key: %w", err)
+	}
+
+	rot := &config.KeyRotation{
+		StartedAt:    time.Now().Format(time.RFC3339),
+		OldPublicKey: oldPublicKey,
+		NewPublicKey: newPublicKey,
+		NewKeyFile:   newKeyPath,
+		Vaults:       map[string]string{},
+	}
+	for _, url := range targets {
+		rot.Vaults[url] = config.RotationPending
+		// Remember vaults passed with --vault for the next rotation
+		if err := config.RememberVault(url); err != nil && verbose {
+			fmt.Printf("⚠️  Could not record vault in global config: %v\n", err)
+		}
+	}
+	if err := rot.Save(); err != nil {
+		return nil, fmt.Errorf("failed to save rotation progress: %w", err)
+	}
+
+	fmt.Printf("🔑 Generated new age key: %s\n", newPublicKey)
+	return rot, nil
+}
+
+// rotateVaultKey moves one vault to its next rotation step and returns its
+// new status. Pending vaults get the new key added next to the old one;
+// vaults whose add PR has been merged get the old key removed.
+func rotateVaultKey(repoURL string, rot *config.KeyRotation) (string, error) {
+	status := rot.Vaults[repoURL]
+	adding := status == config.RotationPending
+
+	vman, err := syncVault(repoURL)
+	if err != nil {
+		return status, err
+	}
+	defer vman.Cleanup()
+
+	repoDir := filepath.Join(vman.WorkDir, "repo")
+	sopsPath := filepath.Join(repoDir, ".sops.yaml")
+	groupsPath := filepath.Join(repoDir, project.GroupsFile)
+
+	before, err := project.LoadSOPSConfig(sopsPath)
+	if os.IsNotExist(err) {
+		return config.RotationSkipped, nil
+	}
+	if err != nil {
+		return status, fmt.Errorf("failed to read vault .sops.yaml: %w", err)
+	}
+
+	if !adding {
+		merged := false
+		for _, rule := range before.CreationRules {
+			if rule.Age.Contains(rot.NewPublicKey) {
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			return status, nil
+		}
+	}
+
+	found := false
+	sopsCfg := before.Clone()
+	for i := range sopsCfg.CreationRules {
+		rule := &sopsCfg.CreationRules[i]
+		if !rule.Age.Contains(rot.OldPublicKey) {
+			continue
+		}
+		found = true
+		if !rule.Age.Contains(rot.NewPublicKey) {
+			rule.Age = append(rule.Age, rot.NewPublicKey)
+		}
+		if !adding {
+			rule.Age = rule.Age.Without(rot.OldPublicKey)
+		}
+	}
+
+	if !found {
+		if adding {
+			return config.RotationSkipped, nil
+		}
+		return config.RotationDone, nil
+	}
+
+	if err := sopsCfg.Save(sopsPath); err != nil {
+		return status, err
+	}
+
+	// Swap group memberships once the old key is gone, so regenerating
+	// .sops.yaml from groups keeps the new key
+	if !adding {
+		groups, err := project.LoadGroups(groupsPath)
+		if err != nil {
+			return status, err
+		}
+		swapped := false
+		for _, g := range groups.Groups {
+			for i := range g.Members {
+				if g.Members[i].Key == rot.OldPublicKey {
+					g.Members[i].Key = rot.NewPublicKey
+					swapped = true
+				}
+			}
+		}
+		if swapped {
+			if err := groups.Save(groupsPath); err != nil {
+				return status, err
+			}
+		}
+	}
+
+	// The old key is still installed and can decrypt every file it's removed from
+	if _, err := reencryptChanged(repoDir, store.NewAgeEncryptor(), before, sopsCfg); err != nil {
+		return status, fmt.Errorf("failed to rekey vault: %w", err)
+	}
+
+	msg := fmt.Sprintf("rotate key: add %s", rot.NewPublicKey)
+	next := config.RotationAdded
+	if !adding {
+		msg = fmt.Sprintf("rotate key: remove %s", rot.OldPublicKey)
+		next = config.RotationDone
+	}
+
+	if err := vman.CommitAndPush(".", msg, true); err != nil {
+		return status, fmt.Errorf("failed to commit and create PR: %w", err)
+	}
+	return next, nil
+}
+
+// finishKeyRotation installs the new key, updates the key-sync backup and
+// clears the rotation progress
+func finishKeyRotation(rot *config.KeyRotation) error {
+	keyPath, err := config.GetAgeKeyPath()
+	if err != nil {
+		return err
+	}
+	pubPath, err := config.GetAgePublicKeyPath()
+	if err != nil {
+		return err
+	}
+
+	// Keep the old key: git history is still encrypted to it. A resumed
+	// rotation may have installed the new key already
+	oldKeyPath := fmt.Sprintf("%s.%s.old", keyPath, time.Now().Format("20060102150405"))
+	moved := false
+	if _, err := os.Stat(rot.NewKeyFile); err == nil {
+		if err := os.Rename(keyPath, oldKeyPath); err != nil {
+			return fmt.Errorf("failed to move old key aside: %w", err)
+		}
+		moved = true
+		if err := os.Rename(rot.NewKeyFile, keyPath); err != nil {
+			return fmt.Errorf("failed to install new key: %w", err)
+		}
+	}
+	if err := os.WriteFile(pubPath, []byte(rot.NewPublicKey), 0o644); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ New age key installed: %s\n", keyPath)
+	if moved {
+		fmt.Printf("🗄️  Old key kept at %s for decrypting git history\n", oldKeyPath)
+	}
+
+	repoName, exists, err := keyBackupRepo()
+	switch {
+	case err != nil:
+		fmt.Printf("⚠️  Could not check key backup: %v\n", err)
+	case !exists:
+		fmt.Println("💡 No key-sync backup repository - run 'yoink key-sync setup' to back up the new key")
+	default:
+		if err := pushKeyBackup(keyPath, "Rotate Age key"); err != nil {
+			fmt.Printf("⚠️  Failed to update key backup in %s: %v\n", repoName, err)
+		} else {
+			fmt.Println("✅ Key backup updated")
+		}
+	}
+
+	if err := config.ClearKeyRotation(); err != nil {
+		return fmt.Errorf("failed to clear rotation progress: %w", err)
+	}
+
+	fmt.Println("🎉 Key rotation complete")
+	return nil
+}
+
+// abortKeyRotation abandons a rotation whose add PRs won't be merged: the
+// progress and the new key are removed and the current key stays in use.
+// Once a vault has had the old key removed only the new key can read it, so
+// the rotation has to be finished instead
+func abortKeyRotation(rot *config.KeyRotation) error {
+	if rot == nil {
+		return fmt.Errorf("no key rotation in progress")
+	}
+	for _, url := range sortedVaults(rot) {
+		if rot.Vaults[url] == config.RotationDone {
+			return fmt.Errorf("the old key has already been removed from %s - run 'yoink rotate key' to finish the rotation instead", url)
+		}
+	}
+
+	if dryRun {
+		fmt.Printf("🔍 [DRY RUN] Would abandon the rotation from %s to %s and delete %s\n", rot.OldPublicKey, rot.NewPublicKey, rot.NewKeyFile)
+		return nil
+	}
+
+	if err := os.Remove(rot.NewKeyFile); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove new key: %w", err)
+	}
+	if err := config.ClearKeyRotation(); err != nil {
+		return fmt.Errorf("failed to clear rotation progress: %w", err)
+	}
+
+	fmt.Printf("🛑 Key rotation abandoned, still using %s\n", rot.OldPublicKey)
+	for _, url := range sortedVaults(rot) {
+		if rot.Vaults[url] == config.RotationAdded {
+			fmt.Printf("💡 %s: close the PR adding %s, or if it was merged remove the key with 'yoink remove-user'\n", url, rot.NewPublicKey)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,527 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jack-kitto/yoink/internal/store"
+	"github.com/jack-kitto/yoink/internal/util"
+	"github.com/jack-kitto/yoink/internal/vault"
+	"github.com/spf13/cobra"
+)
+
+func runCmd() *cobra.Command {
+	var files []string
+	var only []string
+	var noInheritEnv bool
+	var execInPlace bool
+	var watch bool
+	var interval time.Duration
+	var filter store.Filter
+
+	cmd := &cobra.Command{
+		Use:   "run -- <command>",
+		Short: "Run a command with secrets injected as environment variables",
+		Long: `Run a command with secrets injected as environment variables.
+
+yoink stays out of the way: signals it receives are forwarded to the command
+and it exits with the command's exit status (128+N if it was killed by signal
+N). With --exec yoink replaces itself with the command instead, so nothing is
+left in between.
+
+The command inherits yoink's environment plus the secrets. --only KEY1,KEY2
+injects just those secrets, and --no-inherit-env starts from an empty
+environment (not even PATH) so the command sees exactly what yoink gives it.
+--prefix, --strip-prefix, --include, --exclude and --map select and rename
+secrets first; --only and --file refer to the resulting names.
+
+Tools that want a credentials file rather than a value (GCP service account
+JSON, kubeconfig, TLS keys) can be given one with --file KEY:ENVVAR: the
+secret is written to a private 0600 temp file, preferably on memory-backed
+storage, and ENVVAR is set to its path instead of KEY being set to the value.
+The files are shredded once the command exits, including when yoink is
+interrupted with SIGINT or SIGTERM.
+
+With --watch yoink polls the vault every --interval (asking for the default
+branch's commit, fetching the secrets only when it moved) and, when the secrets
+the command sees change, stops it and everything it started with SIGTERM and
+starts it again with the new values, printing the names of the keys that
+changed.
+
+  yoink run --file GCP_SA_JSON:GOOGLE_APPLICATION_CREDENTIALS -- terraform plan
+  yoink run --watch --interval 10s -- npm run dev`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := ensureConfigLoaded(); err != nil {
+				return err
+			}
+
+			if err := filter.Validate(); err != nil {
+				return err
+			}
+
+			mounts, err := parseFileMounts(files)
+			if err != nil {
+				return err
+			}
+			if execInPlace && len(mounts) > 0 {
+				return fmt.Errorf("--exec can't be combined with --file, as nothing would be left to remove the files")
+			}
+			if execInPlace && watch {
+				return fmt.Errorf("--exec can't be combined with --watch, as nothing would be left to restart the command")
+			}
+			if watch && interval <= 0 {
+				return fmt.Errorf("--interval must be positive")
+			}
+			if watch && atRevision != "" {
+				return fmt.Errorf("--watch can't be combined with --at, as a past revision never changes")
+			}
+			if err := resolveAt(); err != nil {
+				return err
+			}
+
+			// load fetches the secrets the command may see, under the
+			// names it sees them by
+			load := func() (map[string]string, error) {
+				envMap, err := fetchSecrets()
+				if err != nil {
+					return nil, err
+				}
+				return filter.Apply(envMap)
+			}
+
+			envMap, err := load()
+			if err != nil {
+				return err
+			}
+
+			for _, m := range mounts {
+				if _, ok := envMap[m.Key]; !ok {
+					return fmt.Errorf("secret %s not found", m.Key)
+				}
+			}
+			injected, err := selectSecrets(envMap, only, mounts)
+			if err != nil {
+				return err
+			}
+
+			if dryRun {
+				fmt.Println("🔍 [DRY RUN] Would run command with injected secrets:")
+				for k := range injected {
+					fmt.Printf("  %s=***\n", k)
+				}
+				for _, m := range mounts {
+					fmt.Printf("  %s=<temp file with %s>\n", m.EnvVar, m.Key)
+				}
+				fmt.Printf("Command: %v\n", args)
+				return nil
+			}
+
+			// Start with current environment, unless told otherwise
+			var baseEnv []string
+			if !noInheritEnv {
+				baseEnv = os.Environ()
+			}
+
+			count := len(injected) + len(mounts)
+			if !verbose {
+				fmt.Fprintf(os.Stderr, "🚀 Running command with %d secrets...\n", count)
+			} else {
+				fmt.Fprintf(os.Stderr, "🚀 Running command with %d injected secrets: %v\n", count, args)
+			}
+
+			if execInPlace {
+				path, err := exec.LookPath(args[0])
+				if err != nil {
+					return err
+				}
+				return execCommand(path, args, secretEnv(baseEnv, injected, nil, nil))
+			}
+
+			// prepare builds the command for a set of secrets, writing its
+			// secret files; cleanup removes them again
+			prepare := func(envMap map[string]string) (c *exec.Cmd, cleanup func(), err error) {
+				injected, err := selectSecrets(envMap, only, mounts)
+				if err != nil {
+					return nil, nil, err
+				}
+
+				paths, err := writeSecretFiles(envMap, mounts)
+				cleanup = func() { removeSecretFiles(paths) }
+				if err != nil {
+					cleanup()
+					return nil, nil, err
+				}
+
+				// Execute the command with inherited streams
+				c = exec.Command(args[0], args[1:]...)
+				c.Env = secretEnv(baseEnv, injected, mounts, paths)
+				c.Stdout = os.Stdout
+				c.Stderr = os.Stderr
+				c.Stdin = os.Stdin
+				return c, cleanup, nil
+			}
+
+			// Catch signals from here on so yoink outlives the command,
+			// forwards them to it and always gets to remove the secret files
+			sigs := make(chan os.Signal, 1)
+			signal.Notify(sigs, forwardedSignals...)
+			defer signal.Stop(sigs)
+
+			if watch {
+				err = watchChild(load, prepare, envMap, relevantKeys(only, mounts), sigs, interval)
+			} else {
+				var c *exec.Cmd
+				var cleanup func()
+				if c, cleanup, err = prepare(envMap); err != nil {
+					return err
+				}
+				err = runChild(c, sigs)
+				cleanup()
+			}
+
+			var status exitStatus
+			if errors.As(err, &status) {
+				cmd.SilenceErrors = true
+				cmd.SilenceUsage = true
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&files, "file", nil, "write secret KEY to a private temp file and set ENVVAR to its path (KEY:ENVVAR, repeatable)")
+	cmd.Flags().StringSliceVar(&only, "only", nil, "inject only these secrets (comma-separated)")
+	cmd.Flags().BoolVar(&noInheritEnv, "no-inherit-env", false, "don't pass yoink's own environment to the command")
+	cmd.Flags().BoolVar(&execInPlace, "exec", false, "replace yoink with the command instead of running it as a child")
+	cmd.Flags().BoolVar(&watch, "watch", false, "restart the command when the vault's secrets change")
+	cmd.Flags().DurationVar(&interval, "interval", 30*time.Second, "how often --watch polls the vault")
+	atFlag(cmd)
+	filterFlags(cmd, &filter)
+	return cmd
+}
+
+// exitStatus is returned when a wrapped command fails, so yoink exits with the
+// command's own status and without printing anything
+type exitStatus int
+
+func (e exitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+// fetchSecrets loads the selected environment's secrets through the fast
+// store, falling back to a temporary vault clone
+func fetchSecrets() (map[string]string, error) {
+	// Try fast fetch first
+	fs := newFastStore()
+	envMap, err := fs.All()
+	if err == nil {
+		return envMap, nil
+	}
+	if verbose {
+		fmt.Printf("⚠️  Fast fetch failed (%v), falling back to git clone...\n", err)
+	}
+
+	// Fallback to traditional method if fast fetch fails
+	vman, err := vault.New(projectCfg.VaultRepo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize vault: %w", err)
+	}
+	vman.Verbose = verbose
+
+	defer vman.Cleanup()
+
+	if err := vman.Sync(); err != nil {
+		return nil, fmt.Errorf("failed to sync vault: %w", err)
+	}
+	if err := checkoutAt(vman); err != nil {
+		return nil, err
+	}
+
+	encPath := filepath.Join(vman.WorkDir, "repo", secretsFile())
+	s := store.New(encPath, encryptor)
+	envMap, err = s.All()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load secrets: %w", err)
+	}
+	return envMap, nil
+}
+
+// selectSecrets returns the secrets to inject as variables: those named by
+// --only (all when empty), minus the ones handed over as files
+func selectSecrets(envMap map[string]string, only []string, mounts fileMounts) (map[string]string, error) {
+	selected := make(map[string]string)
+	if len(only) == 0 {
+		for k, v := range envMap {
+			selected[k] = v
+		}
+	}
+	for _, k := range only {
+		v, ok := envMap[k]
+		if !ok {
+			return nil, fmt.Errorf("secret %s not found", k)
+		}
+		selected[k] = v
+	}
+
+	for _, m := range mounts {
+		delete(selected, m.Key)
+	}
+	return selected, nil
+}
+
+// secretEnv appends secrets and the paths of secret files to an environment
+func secretEnv(base []string, injected map[string]string, mounts fileMounts, paths []string) []string {
+	env := append([]string{}, base...)
+
+	// Add secrets as environment variables
+	for k, v := range injected {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+	for i, m := range mounts {
+		env = append(env, fmt.Sprintf("%s=%s", m.EnvVar, paths[i]))
+	}
+	return env
+}
+
+// child is a running command that yoink forwards signals to
+type child struct {
+	cmd         *exec.Cmd
+	interactive bool
+	done        chan error
+}
+
+// startChild starts a command in a process group of its own, so signals
+// reach everything it starts. From the foreground of a terminal the group
+// also takes over the terminal, so it gets Ctrl-C and friends straight from it
+func startChild(c *exec.Cmd) (*child, error) {
+	ch := &child{cmd: c, interactive: foregroundTerminal(), done: make(chan error, 1)}
+	setProcessGroup(c, ch.interactive)
+
+	if err := c.Start(); err != nil {
+		return nil, err
+	}
+	wait := c.Wait
+	if ch.interactive {
+		wait = func() error { return waitChild(c) }
+	}
+	go func() { ch.done <- wait() }()
+	return ch, nil
+}
+
+// forward passes on a signal yoink received, unless the terminal already
+// delivered it to the command. The terminal only signals yoink itself once
+// it is back in the foreground, say after bg and fg
+func (ch *child) forward(sig os.Signal) {
+	if ch.interactive && fromTerminal(sig) && !foregroundTerminal() {
+		return
+	}
+	signalChild(ch.cmd, sig, true)
+}
+
+// stop asks the command to exit and kills it if it hasn't within the timeout
+func (ch *child) stop(timeout time.Duration) {
+	signalChild(ch.cmd, stopSignal, true)
+	select {
+	case <-ch.done:
+	case <-time.After(timeout):
+		signalChild(ch.cmd, os.Kill, true)
+		<-ch.done
+	}
+}
+
+// result turns the command's exit into yoink's: nil or an exitStatus
+func result(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitStatus(exitCode(exitErr.ProcessState))
+	}
+	return err
+}
+
+// runChild runs a command until it exits, forwarding the signals yoink
+// receives, and turns a non-zero exit into an exitStatus
+func runChild(c *exec.Cmd, sigs <-chan os.Signal) error {
+	ch, err := startChild(c)
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case sig := <-sigs:
+			ch.forward(sig)
+		case err := <-ch.done:
+			return result(err)
+		}
+	}
+}
+
+// watchChild runs a command like runChild, restarting it with fresh secrets
+// whenever the vault changes in a way the command can see
+func watchChild(load func() (map[string]string, error), prepare func(map[string]string) (*exec.Cmd, func(), error), current map[string]string, relevant func(string) bool, sigs <-chan os.Signal, interval time.Duration) error {
+	vaultHead := newFastStore()
+	head, _ := vaultHead.Commit()
+
+	c, cleanup, err := prepare(current)
+	if err != nil {
+		return err
+	}
+	defer func() { cleanup() }()
+
+	ch, err := startChild(c)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case sig := <-sigs:
+			ch.forward(sig)
+
+		case err := <-ch.done:
+			return result(err)
+
+		case <-ticker.C:
+			// Only fetch and decrypt when the vault branch moved, or when
+			// we can't tell
+			newHead, err := vaultHead.Commit()
+			if err == nil && newHead == head {
+				continue
+			}
+
+			next, err := load()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  Failed to check the vault for changes: %v\n", err)
+				continue
+			}
+			head = newHead
+
+			changed := changedKeys(current, next, relevant)
+			if len(changed) == 0 {
+				continue
+			}
+
+			nextCmd, nextCleanup, err := prepare(next)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  Secrets changed (%s) but the command can't be restarted: %v\n", strings.Join(changed, ", "), err)
+				continue
+			}
+
+			fmt.Fprintf(os.Stderr, "🔄 Secrets changed (%s), restarting command...\n", strings.Join(changed, ", "))
+			ch.stop(10 * time.Second)
+			cleanup()
+
+			c, cleanup, current = nextCmd, nextCleanup, next
+			if ch, err = startChild(c); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// relevantKeys reports which secrets the command sees, given --only and --file
+func relevantKeys(only []string, mounts fileMounts) func(string) bool {
+	return func(key string) bool {
+		if len(only) == 0 {
+			return true
+		}
+		for _, k := range only {
+			if k == key {
+				return true
+			}
+		}
+		for _, m := range mounts {
+			if m.Key == key {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// changedKeys returns the sorted names of relevant secrets that were added,
+// changed or removed
+func changedKeys(before, after map[string]string, relevant func(string) bool) []string {
+	var changed []string
+	for k, v := range after {
+		if old, ok := before[k]; (!ok || old != v) && relevant(k) {
+			changed = append(changed, k)
+		}
+	}
+	for k := range before {
+		if _, ok := after[k]; !ok && relevant(k) {
+			changed = append(changed, k)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// fileMount is a secret handed to the command as a file path
+type fileMount struct {
+	Key    string
+	EnvVar string
+}
+
+type fileMounts []fileMount
+
+// parseFileMounts parses KEY:ENVVAR arguments; ENVVAR defaults to KEY
+func parseFileMounts(args []string) (fileMounts, error) {
+	var mounts fileMounts
+	seen := make(map[string]bool)
+	for _, arg := range args {
+		key, envVar, ok := strings.Cut(arg, ":")
+		if !ok {
+			envVar = key
+		}
+		if key == "" || envVar == "" {
+			return nil, fmt.Errorf("invalid --file %q (expected KEY:ENVVAR)", arg)
+		}
+		if seen[envVar] {
+			return nil, fmt.Errorf("%s is set by more than one --file", envVar)
+		}
+		seen[envVar] = true
+		mounts = append(mounts, fileMount{Key: key, EnvVar: envVar})
+	}
+	return mounts, nil
+}
+
+// writeSecretFiles writes each mounted secret to its own private temp file
+// and returns their paths, including those written before any error
+func writeSecretFiles(envMap map[string]string, mounts fileMounts) ([]string, error) {
+	var paths []string
+	for _, m := range mounts {
+		f, err := util.CreatePrivateTemp("yoink-" + strings.ToLower(m.EnvVar) + "-*")
+		if err != nil {
+			return paths, fmt.Errorf("failed to create file for %s: %w", m.Key, err)
+		}
+		paths = append(paths, f.Name())
+
+		_, err = f.WriteString(envMap[m.Key])
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return paths, fmt.Errorf("failed to write file for %s: %w", m.Key, err)
+		}
+	}
+	return paths, nil
+}
+
+func removeSecretFiles(paths []string) {
+	for _, p := range paths {
+		if err := util.ShredFile(p); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Failed to remove %s: %v\n", p, err)
+		}
+	}
+}
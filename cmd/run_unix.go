@@ -0,0 +1,145 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+// forwardedSignals are the signals yoink run passes on to the command
+var forwardedSignals = []os.Signal{
+	syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT,
+	syscall.SIGUSR1, syscall.SIGUSR2, syscall.SIGWINCH,
+}
+
+// exitSignals are the signals that end yoink, which it catches while it
+// waits for an editor so it can still clean up
+var exitSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP}
+
+// stopSignal asks a command to exit before it is restarted
+var stopSignal os.Signal = syscall.SIGTERM
+
+// fromTerminal reports whether the terminal sends sig to the whole
+// foreground process group, so the command already has it
+func fromTerminal(sig os.Signal) bool {
+	return sig == syscall.SIGINT || sig == syscall.SIGQUIT || sig == syscall.SIGWINCH
+}
+
+// setProcessGroup puts the command in a process group of its own. With
+// foreground set the group also takes over the terminal, the way a shell
+// starts a job
+func setProcessGroup(c *exec.Cmd, foreground bool) {
+	c.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Foreground: foreground, Ctty: int(os.Stdin.Fd())}
+}
+
+// foregroundTerminal reports whether yoink runs in the foreground of a terminal
+func foregroundTerminal() bool {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return false
+	}
+	pgrp, err := unix.IoctlGetInt(fd, unix.TIOCGPGRP)
+	return err == nil && pgrp == unix.Getpgrp()
+}
+
+// moveTerminal hands the terminal from one process group to another, unless
+// it has moved on already
+func moveTerminal(from, to int) {
+	fd := int(os.Stdin.Fd())
+	if pgrp, err := unix.IoctlGetInt(fd, unix.TIOCGPGRP); err != nil || pgrp != from {
+		return
+	}
+	// Taking the terminal from the background raises SIGTTOU, which would
+	// stop yoink
+	signal.Ignore(syscall.SIGTTOU)
+	defer signal.Reset(syscall.SIGTTOU)
+	unix.IoctlSetPointerInt(fd, unix.TIOCSPGRP, to)
+}
+
+// waitChild waits for a command that took over the terminal and gives the
+// terminal back once it exits. When Ctrl-Z stops the command, yoink takes
+// the terminal back and stops itself too, so the shell sees a stopped job;
+// fg or bg then resumes the command along with yoink. c.Wait can't report
+// a stop, so this reaps the command with wait4 itself
+func waitChild(c *exec.Cmd) error {
+	pid := c.Process.Pid
+	defer c.Process.Release()
+
+	cont := make(chan os.Signal, 1)
+	signal.Notify(cont, syscall.SIGCONT)
+	defer signal.Stop(cont)
+	exited := make(chan struct{})
+	defer close(exited)
+	go func() {
+		for {
+			select {
+			case <-cont:
+				if foregroundTerminal() {
+					moveTerminal(unix.Getpgrp(), pid)
+				}
+				syscall.Kill(-pid, syscall.SIGCONT)
+			case <-exited:
+				return
+			}
+		}
+	}()
+
+	for {
+		var ws syscall.WaitStatus
+		if _, err := syscall.Wait4(pid, &ws, syscall.WUNTRACED, nil); err != nil {
+			if err == syscall.EINTR {
+				continue
+			}
+			return err
+		}
+		moveTerminal(pid, unix.Getpgrp())
+		if ws.Stopped() {
+			syscall.Kill(0, syscall.SIGSTOP)
+			continue
+		}
+
+		if code := statusCode(ws); code != 0 {
+			return exitStatus(code)
+		}
+		return nil
+	}
+}
+
+// signalChild sends sig to the command, or to its whole process group
+func signalChild(c *exec.Cmd, sig os.Signal, group bool) {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return
+	}
+	if group {
+		syscall.Kill(-c.Process.Pid, s)
+		return
+	}
+	c.Process.Signal(s)
+}
+
+// exitCode follows the shell convention of 128+N for a command killed by signal N
+func exitCode(state *os.ProcessState) int {
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok {
+		return statusCode(ws)
+	}
+	return state.ExitCode()
+}
+
+func statusCode(ws syscall.WaitStatus) int {
+	if ws.Signaled() {
+		return 128 + int(ws.Signal())
+	}
+	return ws.ExitStatus()
+}
+
+// execCommand replaces yoink with the command
+func execCommand(path string, args, env []string) error {
+	return syscall.Exec(path, args, env)
+}
@@ -0,0 +1,49 @@
+//go:build windows
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"golang.org/x/term"
+)
+
+// forwardedSignals are the signals yoink run passes on to the command
+var forwardedSignals = []os.Signal{os.Interrupt}
+
+// exitSignals are the signals that end yoink; Windows only has Ctrl-C
+var exitSignals = []os.Signal{os.Interrupt}
+
+// stopSignal stops a command before it is restarted; Windows has no SIGTERM
+var stopSignal = os.Kill
+
+// fromTerminal reports whether the console sends sig to the command too,
+// which it does for Ctrl-C
+func fromTerminal(sig os.Signal) bool {
+	return sig == os.Interrupt
+}
+
+func setProcessGroup(c *exec.Cmd, foreground bool) {}
+
+// foregroundTerminal reports whether yoink runs in a console
+func foregroundTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+func waitChild(c *exec.Cmd) error {
+	return c.Wait()
+}
+
+func signalChild(c *exec.Cmd, sig os.Signal, group bool) {
+	c.Process.Signal(sig)
+}
+
+func exitCode(state *os.ProcessState) int {
+	return state.ExitCode()
+}
+
+func execCommand(path string, args, env []string) error {
+	return fmt.Errorf("--exec is not supported on Windows")
+}
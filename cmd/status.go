@@ -2,13 +2,14 @@ package cmd
 
 import (
 	"fmt"
-	"os/exec"
-	"strings"
 
 	"github.com/jack-kitto/yoink/internal/config"
+	"github.com/jack-kitto/yoink/internal/git"
+	"github.com/jack-kitto/yoink/internal/github"
 	"github.com/jack-kitto/yoink/internal/project"
 	"github.com/jack-kitto/yoink/internal/store"
 	"github.com/jack-kitto/yoink/internal/util"
+	"github.com/jack-kitto/yoink/internal/vault"
 	"github.com/spf13/cobra"
 )
 
@@ -24,7 +25,7 @@ func statusCmd() *cobra.Command {
 			if err := checkDependencies(); err != nil {
 				fmt.Printf("❌ Dependencies: %v\n", err)
 			} else {
-				fmt.Println("✅ Dependencies: sops, age, git, gh")
+				fmt.Println("✅ Dependencies: git")
 			}
 
 			// Check global config
@@ -45,12 +46,15 @@ func statusCmd() *cobra.Command {
 			}
 
 			// Check project config
+			vaultRepo := ""
 			if projectCfg, err := project.LoadProject(); err != nil {
 				fmt.Printf("⚠️  Project config: %v\n", err)
 				fmt.Println("   Run 'yoink vault-init' in a project directory")
 			} else {
 				fmt.Println("✅ Project config loaded")
 				fmt.Printf("   Vault: %s\n", projectCfg.VaultRepo)
+				vaultRepo = projectCfg.VaultRepo
+				applyForgeConfig(projectCfg)
 
 				// Test vault accessibility
 				if err := testVaultAccess(projectCfg.VaultRepo); err != nil {
@@ -60,22 +64,19 @@ func statusCmd() *cobra.Command {
 				}
 
 				// Test decryption capability
-				if err := testDecryption(projectCfg.VaultRepo); err != nil {
+				if err := testDecryption(projectCfg); err != nil {
 					fmt.Printf("❌ Decryption test: %v\n", err)
 				} else {
 					fmt.Println("✅ Able to decrypt secrets")
 				}
 			}
 
-			// Check GitHub auth status (only if gh is available)
-			if _, err := exec.LookPath("gh"); err != nil {
-				fmt.Println("⚠️  GitHub CLI (gh) not available")
+			// Check GitHub auth status, against the vault's GitHub host
+			// when it has one
+			if login, host, err := checkGitHubAuth(vaultRepo); err != nil {
+				fmt.Printf("⚠️  GitHub auth: %v\n", err)
 			} else {
-				if err := checkGitHubAuth(); err != nil {
-					fmt.Printf("⚠️  GitHub auth: %v\n", err)
-				} else {
-					fmt.Println("✅ GitHub authenticated")
-				}
+				fmt.Printf("✅ GitHub authenticated as @%s (%s)\n", login, host)
 			}
 
 			return nil
@@ -88,38 +89,28 @@ func checkDependencies() error {
 }
 
 func testVaultAccess(vaultRepo string) error {
-	// Try to fetch a file (even if it doesn't exist, we'll get a proper HTTP response)
-	_, err := util.FetchRawVaultFile(vaultRepo, "main", ".sops.yaml")
+	_, err := vault.RemoteHead(vaultRepo, "")
+	return err
+}
+
+func testDecryption(projectCfg project.ProjectConfig) error {
+	enc, err := store.NewEncryptor(projectCfg.Encryption)
 	if err != nil {
-		// Fallback to git ls-remote
-		cmd := exec.Command("git", "ls-remote", vaultRepo)
-		return cmd.Run()
+		return err
 	}
-	return nil
-}
 
-func testDecryption(vaultRepo string) error {
 	// Try to fetch and decrypt secrets file
-	fs := store.NewFast(vaultRepo)
-	_, err := fs.Keys()
+	fs := store.NewFast(projectCfg.VaultRepo, enc)
+	_, err = fs.Keys()
 	return err
 }
 
-func checkGitHubAuth() error {
-	cmd := exec.Command("gh", "auth", "status")
-	output, err := cmd.CombinedOutput()
-
-	// gh auth status returns exit code 1 even when authenticated
-	// Check the output content instead
-	outputStr := string(output)
-	if strings.Contains(outputStr, "Logged in to github.com") ||
-		strings.Contains(outputStr, "✓ Logged in to github.com") {
-		return nil
-	}
-
+func checkGitHubAuth(vaultRepo string) (string, string, error) {
+	client, _, err := git.GitHubClient(vaultRepo)
 	if err != nil {
-		return fmt.Errorf("not authenticated - run 'gh auth login'")
+		client = github.New("", "")
 	}
 
-	return fmt.Errorf("authentication status unclear")
+	login, err := client.CurrentUser()
+	return login, client.Host, err
 }
@@ -3,12 +3,14 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"github.com/jack-kitto/yoink/internal/config"
 	"github.com/jack-kitto/yoink/internal/git"
+	"github.com/jack-kitto/yoink/internal/github"
 	"github.com/jack-kitto/yoink/internal/project"
+	"github.com/jack-kitto/yoink/internal/store"
 	"github.com/spf13/cobra"
 )
 
@@ -46,8 +48,23 @@ func onboardCmd() *cobra.Command {
 
 			publicKey := strings.TrimSpace(string(pubKeyData))
 
+			// Onboarding forks the vault, which only works on GitHub
+			applyForgeConfig(projectCfg)
+			forge, err := git.ForgeFor(projectCfg.VaultRepo)
+			if err != nil {
+				return err
+			}
+			if forge.Name() != git.ForgeGitHub {
+				fmt.Printf("ℹ️  Your public key: %s\n", publicKey)
+				return fmt.Errorf("onboarding PRs need a GitHub vault - send your public key to a vault maintainer instead")
+			}
+
 			// Get current user info
-			username, err := getCurrentGitHubUser()
+			client, _, err := git.GitHubClient(projectCfg.VaultRepo)
+			if err != nil {
+				return err
+			}
+			username, err := client.CurrentUser()
 			if err != nil {
 				return fmt.Errorf("failed to get GitHub username: %w", err)
 			}
@@ -64,11 +81,13 @@ func onboardCmd() *cobra.Command {
 
 			fmt.Println("📤 Creating GitHub pull request...")
 
-			if err := git.ForkAndCreatePR(projectCfg.VaultRepo, title, body); err != nil {
+			keyFile := fmt.Sprintf("onboarding/%s.age.pub", username)
+			url, err := git.ForkAndCreatePR(projectCfg.VaultRepo, keyFile, []byte(publicKey+"\n"), title, body)
+			if err != nil {
 				return fmt.Errorf("failed to create PR: %w", err)
 			}
 
-			fmt.Println("✅ Pull request created!")
+			fmt.Printf("✅ Pull request created: %s\n", url)
 			fmt.Println("💭 Maintainers must merge the PR to grant you access to the vault.")
 
 			return nil
@@ -79,64 +98,113 @@ func onboardCmd() *cobra.Command {
 func removeUserCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "remove-user <public-key>",
-		Short: "Create a PR to remove a user's access to the vault",
+		Short: "Remove a user's key from the vault and rekey their files (creates a PR)",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// Ensure we're in a project
-			projectCfg, err := project.LoadProject()
-			if err != nil {
-				return fmt.Errorf("not in a yoink project: %w", err)
+			if err := ensureConfigLoaded(); err != nil {
+				return err
+			}
+			if projectCfg.Encryption == store.EncryptionPassphrase {
+				return fmt.Errorf("passphrase vaults have no per-user keys - change the passphrase and rekey instead")
 			}
 
+			publicKey := strings.TrimSpace(args[0])
+
 			if dryRun {
-				fmt.Printf("🔍 [DRY RUN] Would create PR to remove user: %s\n", args[0])
+				fmt.Printf("🔍 [DRY RUN] Would remove %s from .sops.yaml and its groups, and rekey the files it could decrypt\n", publicKey)
 				return nil
 			}
 
-			publicKey := strings.TrimSpace(args[0])
+			vman, err := syncedVault()
+			if err != nil {
+				return err
+			}
+			defer vman.Cleanup()
 
-			// Get current user info
-			username, err := getCurrentGitHubUser()
+			repoDir := filepath.Join(vman.WorkDir, "repo")
+			sopsPath := filepath.Join(repoDir, ".sops.yaml")
+			groupsPath := filepath.Join(repoDir, project.GroupsFile)
+
+			before, err := project.LoadSOPSConfig(sopsPath)
 			if err != nil {
-				return fmt.Errorf("failed to get GitHub username: %w", err)
+				return fmt.Errorf("failed to read vault .sops.yaml: %w", err)
+			}
+			groups, err := project.LoadGroups(groupsPath)
+			if err != nil {
+				return err
 			}
 
-			// Create PR
-			title := "Remove user from vault access"
-			body := fmt.Sprintf("Removing user from vault access.\n\n"+
-				"**Requested by:** %s\n"+
-				"**Public Key to Remove:**\n"+
-				"```\n"+
-				"%s\n"+
-				"```\n\n"+
-				"Please remove this public key from the .sops.yaml configuration.", username, publicKey)
+			// Remove the key from every creation rule it was added to directly
+			found := false
+			sopsCfg := before.Clone()
+			for i := range sopsCfg.CreationRules {
+				list := sopsCfg.CreationRules[i].KeysFor(publicKey)
+				if list.Contains(publicKey) {
+					found = true
+					*list = list.Without(publicKey)
+				}
+			}
 
-			fmt.Println("📤 Creating GitHub pull request...")
+			// Drop group memberships too, and regenerate the rules from the
+			// groups that are left, so the key can't come back through them
+			previous := groups.Grants(before)
+			var removedMembers []string
+			for _, name := range groups.Names() {
+				g := groups.Groups[name]
+				kept := g.Members[:0]
+				for _, m := range g.Members {
+					if m.Key == publicKey {
+						removedMembers = append(removedMembers, fmt.Sprintf("%s (%s)", m.Name, name))
+						continue
+					}
+					kept = append(kept, m)
+				}
+				g.Members = kept
+			}
+			if len(removedMembers) > 0 {
+				found = true
+				groups.ApplyTo(&sopsCfg, previous)
+			}
 
-			if err := git.ForkAndCreatePR(projectCfg.VaultRepo, title, body); err != nil {
-				return fmt.Errorf("failed to create PR: %w", err)
+			if !found {
+				return fmt.Errorf("key %s is not a recipient in the vault's .sops.yaml or a member of any group", publicKey)
+			}
+			for _, rule := range sopsCfg.CreationRules {
+				if len(rule.Age) == 0 && len(rule.PGP) == 0 {
+					return fmt.Errorf("refusing to remove the last recipient of rule %s", rule.PathRegex)
+				}
 			}
 
-			fmt.Println("✅ Pull request created!")
-			fmt.Println("💭 Maintainers must merge the PR to remove user access.")
+			if err := sopsCfg.Save(sopsPath); err != nil {
+				return err
+			}
+			if len(removedMembers) > 0 {
+				if err := groups.Save(groupsPath); err != nil {
+					return err
+				}
+				fmt.Printf("👥 Removed group memberships: %s\n", strings.Join(removedMembers, ", "))
+			}
+
+			// Rotate the data key of every file the removed key could decrypt
+			count, err := reencryptChanged(repoDir, encryptor, before, sopsCfg)
+			if err != nil {
+				return fmt.Errorf("failed to rekey vault: %w", err)
+			}
+
+			fmt.Println("📤 Creating GitHub pull request...")
+			if err := vman.CommitAndPush(".", fmt.Sprintf("remove user %s and rekey", publicKey), true); err != nil {
+				return fmt.Errorf("failed to commit and create PR: %w", err)
+			}
+
+			fmt.Printf("✅ Key removed and %d files rekeyed (PR created)\n", count)
+			fmt.Println("⚠️  Older versions remain decryptable from git history - rotate any secrets the user had access to.")
 
 			return nil
 		},
 	}
 }
 
+// getCurrentGitHubUser returns the login of the user's GitHub token
 func getCurrentGitHubUser() (string, error) {
-	// Try to get username from gh CLI
-	cmd := exec.Command("gh", "api", "user", "--jq", ".login")
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("please authenticate with 'gh auth login' first")
-	}
-
-	username := strings.TrimSpace(string(output))
-	if username == "" {
-		return "", fmt.Errorf("could not determine GitHub username")
-	}
-
-	return username, nil
+	return github.New("", "").CurrentUser()
 }
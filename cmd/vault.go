@@ -16,12 +16,25 @@ import (
 )
 
 func vaultInitCmd() *cobra.Command {
-	return &cobra.Command{
+	var encryption string
+	var pgpKey string
+
+	cmd := &cobra.Command{
 		Use:   "vault-init",
 		Short: "Initialize a per-project vault configuration (.yoink.yaml)",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := store.NewEncryptor(encryption); err != nil {
+				return err
+			}
+			if encryption == "gpg" {
+				encryption = store.EncryptionPGP
+			}
+			if encryption == store.EncryptionPGP && pgpKey == "" {
+				return fmt.Errorf("--pgp-key is required with --encryption=pgp")
+			}
+
 			if dryRun {
-				fmt.Println("🔍 [DRY RUN] Would initialize project vault")
+				fmt.Printf("🔍 [DRY RUN] Would initialize project vault (%s encryption)\n", encryption)
 				return nil
 			}
 
@@ -31,8 +44,9 @@ func vaultInitCmd() *cobra.Command {
 			}
 
 			// Check if already initialized and properly set up
-			if util.FileExists(".yoink.yaml") && util.FileExists(".sops.yaml") {
+			if util.FileExists(".yoink.yaml") && (encryption == store.EncryptionPassphrase || util.FileExists(".sops.yaml")) {
 				fmt.Println("ℹ️  Project vault already initialized")
+				rememberProjectVault()
 				return nil
 			}
 
@@ -42,17 +56,19 @@ func vaultInitCmd() *cobra.Command {
 			}
 
 			// Generate age key if it doesn't exist
-			if err := ensureAgeKey(); err != nil {
-				return fmt.Errorf("failed to set up age key: %w", err)
+			if encryption == store.EncryptionAge {
+				if err := ensureAgeKey(); err != nil {
+					return fmt.Errorf("failed to set up age key: %w", err)
+				}
 			}
 
 			// Initialize the project (this may recreate .yoink.yaml if missing)
-			if err := project.InitProject(); err != nil {
+			if err := project.InitProject(encryption); err != nil {
 				return err
 			}
 
 			// Initialize SOPS configuration in project root
-			if err := initSOPSForProject(); err != nil {
+			if err := initSOPSForProject(encryption, pgpKey); err != nil {
 				return fmt.Errorf("failed to initialize SOPS: %w", err)
 			}
 
@@ -61,12 +77,19 @@ func vaultInitCmd() *cobra.Command {
 				return fmt.Errorf("failed to push SOPS config to vault: %w", err)
 			}
 
+			rememberProjectVault()
+
 			fmt.Println("✅ Project vault initialization complete")
 			fmt.Println("💡 You can now run 'yoink set KEY value' to add secrets")
 
 			return nil
 		},
 	}
+
+	cmd.Flags().StringVar(&encryption, "encryption", store.EncryptionAge, "Encryption mode: age, pgp or passphrase")
+	cmd.Flags().StringVar(&pgpKey, "pgp-key", "", "OpenPGP key fingerprint to encrypt to (with --encryption=pgp)")
+
+	return cmd
 }
 
 // Add this new function to push SOPS config to vault
@@ -132,6 +155,18 @@ func ensureGlobalConfig() error {
 	return nil
 }
 
+// rememberProjectVault records the project's vault in the global config, so
+// 'yoink rotate key' can find it later from any directory
+func rememberProjectVault() {
+	projCfg, err := project.LoadProject()
+	if err != nil {
+		return
+	}
+	if err := config.RememberVault(projCfg.VaultRepo); err != nil && verbose {
+		fmt.Printf("⚠️  Could not record vault in global config: %v\n", err)
+	}
+}
+
 func ensureAgeKey() error {
 	keyPath, err := config.GetAgeKeyPath()
 	if err != nil {
@@ -157,42 +192,36 @@ func ensureAgeKey() error {
 		return err
 	}
 
-	// Generate key using age-keygen
-	cmd := exec.Command("age-keygen", "-o", keyPath)
-	output, err := cmd.CombinedOutput()
+	pubKey, err := store.GenerateAgeKey(keyPath)
 	if err != nil {
-		return fmt.Errorf("age-keygen failed: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("failed to generate age key: %w", err)
 	}
-
-	// Extract public key from the private key file
-	data, err := os.ReadFile(keyPath)
-	if err != nil {
+	if err := os.WriteFile(pubPath, []byte(pubKey), 0o644); err != nil {
 		return err
 	}
 
-	// Find the public key in the comment
-	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
-		if strings.HasPrefix(line, "# public key: ") {
-			pubKey := strings.TrimPrefix(line, "# public key: ")
-			if err := os.WriteFile(pubPath, []byte(pubKey), 0o644); err != nil {
-				return err
-			}
-			break
-		}
-	}
-
 	fmt.Printf("✅ Age key generated: %s\n", keyPath)
 	return nil
 }
 
-func initSOPSForProject() error {
+func initSOPSForProject(encryption, pgpKey string) error {
+	// Passphrase vaults don't use recipient keys
+	if encryption == store.EncryptionPassphrase {
+		fmt.Println("🔐 Using passphrase encryption (set YOINK_PASSPHRASE to skip the prompt)")
+		return nil
+	}
+
 	// Check if .sops.yaml already exists
 	if util.FileExists(".sops.yaml") {
 		fmt.Println("🔐 Using existing SOPS configuration")
 		return nil
 	}
 
+	if encryption == store.EncryptionPGP {
+		fmt.Println("🔐 Creating SOPS configuration...")
+		return store.InitSOPSForProject(".", project.CreationRule{PGP: project.KeyList{pgpKey}})
+	}
+
 	// Read the public key
 	pubPath, err := config.GetAgePublicKeyPath()
 	if err != nil {
@@ -208,5 +237,5 @@ func initSOPSForProject() error {
 
 	// Create .sops.yaml in the project root (not in .yoink directory)
 	fmt.Println("🔐 Creating SOPS configuration...")
-	return store.InitSOPSForProject(".", []string{publicKey})
+	return store.InitSOPSForProject(".", project.CreationRule{Age: project.KeyList{publicKey}})
 }
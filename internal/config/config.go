@@ -9,8 +9,9 @@ import (
 )
 
 type Config struct {
-	SecretsFile  string `yaml:"secrets_file"`
-	DefaultVault string `yaml:"default_vault"`
+	SecretsFile  string   `yaml:"secrets_file"`
+	DefaultVault string   `yaml:"default_vault"`
+	Vaults       []string `yaml:"vaults"`
 }
 
 func configDir() (string, error) {
@@ -47,6 +48,7 @@ func LoadConfig() (Config, error) {
 
 	c.SecretsFile = viper.GetString("secrets_file")
 	c.DefaultVault = viper.GetString("default_vault")
+	c.Vaults = viper.GetStringSlice("vaults")
 
 	if c.SecretsFile == "" {
 		dir, _ := configDir()
@@ -80,6 +82,31 @@ func InitConfig() error {
 	return nil
 }
 
+// RememberVault records a vault URL in the global config so commands that
+// act on every vault the user has access to can find it
+func RememberVault(vaultURL string) error {
+	cfgPath, err := GetConfigPath()
+	if err != nil {
+		return err
+	}
+
+	v := viper.New()
+	v.SetConfigFile(cfgPath)
+	if err := v.ReadInConfig(); err != nil {
+		return err
+	}
+
+	vaults := v.GetStringSlice("vaults")
+	for _, known := range vaults {
+		if known == vaultURL {
+			return nil
+		}
+	}
+
+	v.Set("vaults", append(vaults, vaultURL))
+	return v.WriteConfigAs(cfgPath)
+}
+
 func GetAgeKeyPath() (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Key rotation progress for each vault
+const (
+	RotationPending = "pending"
+	RotationAdded   = "added"
+	RotationDone    = "done"
+	RotationSkipped = "skipped"
+)
+
+// KeyRotation records an in-progress age key rotation so it can be resumed
+type KeyRotation struct {
+	StartedAt    string            `yaml:"started_at"`
+	OldPublicKey string            `yaml:"old_public_key"`
+	NewPublicKey string            `yaml:"new_public_key"`
+	NewKeyFile   string            `yaml:"new_key_file"`
+	Vaults       map[string]string `yaml:"vaults"`
+}
+
+func rotationPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "rotation.yaml"), nil
+}
+
+// GetNewAgeKeyPath returns where the replacement key is kept during a rotation
+func GetNewAgeKeyPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "age.key.new"), nil
+}
+
+// LoadKeyRotation returns the rotation in progress, or nil if there is none
+func LoadKeyRotation() (*KeyRotation, error) {
+	path, err := rotationPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var r KeyRotation
+	if err := yaml.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	if r.Vaults == nil {
+		r.Vaults = map[string]string{}
+	}
+	return &r, nil
+}
+
+// Save persists the rotation progress
+func (r *KeyRotation) Save() error {
+	path, err := rotationPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// ClearKeyRotation removes the rotation progress file
+func ClearKeyRotation() error {
+	path, err := rotationPath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package git
+
+import (
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+)
+
+// Forge kinds, as set with forge: in .yoink.yaml
+const (
+	ForgeGitHub = "github"
+	ForgeGitLab = "gitlab"
+	ForgeGitea  = "gitea"
+	ForgePlain  = "git"
+)
+
+// Forge publishes vault changes for review on the service hosting the vault
+type Forge interface {
+	// Name is the forge kind
+	Name() string
+	// Propose pushes a branch committed in repoDir and opens a pull request
+	// for it, returning its URL when the forge reports one
+	Propose(repoDir string, pr PullRequest) (string, error)
+}
+
+// PullRequest describes a proposed change to a vault
+type PullRequest struct {
+	Branch string
+	Base   string
+	Title  string
+	Body   string
+}
+
+// ForgeConfig overrides how a vault's forge is chosen
+type ForgeConfig struct {
+	// Kind is one of the Forge* kinds; detected from the URL when empty
+	Kind string
+	// URL is the forge's web address, for self-hosted instances whose API
+	// isn't served from the git remote's host
+	URL string
+}
+
+// Remote is a parsed git remote URL
+type Remote struct {
+	// Host is the remote's host name, empty for local repositories
+	Host string
+	// Path is the repository path on the host (owner/repo, or
+	// group/subgroup/repo on GitLab) without .git, or the local path
+	Path string
+}
+
+// Local reports whether the remote is a repository on this machine
+func (r Remote) Local() bool {
+	return r.Host == ""
+}
+
+// ParseRemote understands scp-style (git@host:owner/repo.git), URL-style
+// (https://, ssh://, git://, file://) and plain local path remotes
+func ParseRemote(remote string) Remote {
+	if i := strings.Index(remote, "://"); i >= 0 {
+		u, err := url.Parse(remote)
+		if err != nil {
+			return Remote{Path: remote}
+		}
+		if u.Scheme == "file" {
+			return Remote{Path: u.Path}
+		}
+		return Remote{Host: u.Hostname(), Path: trimRepoPath(u.Path)}
+	}
+
+	// scp-style: [user@]host:path, as long as the colon comes before any
+	// slash and isn't a Windows drive letter
+	if colon := strings.Index(remote, ":"); colon > 1 {
+		if slash := strings.Index(remote, "/"); slash < 0 || colon < slash {
+			host := remote[:colon]
+			if at := strings.LastIndex(host, "@"); at >= 0 {
+				host = host[at+1:]
+			}
+			return Remote{Host: host, Path: trimRepoPath(remote[colon+1:])}
+		}
+	}
+
+	return Remote{Path: remote}
+}
+
+func trimRepoPath(p string) string {
+	return strings.TrimSuffix(strings.Trim(p, "/"), ".git")
+}
+
+var forgeConfigs = map[string]ForgeConfig{}
+
+// SetForgeConfig records the forge configured for a vault in .yoink.yaml,
+// taking precedence over detection from its URL
+func SetForgeConfig(repoURL string, cfg ForgeConfig) {
+	forgeConfigs[repoURL] = cfg
+}
+
+// ForgeFor returns the forge for a vault: the one configured with
+// SetForgeConfig, or one detected from the vault URL's host. Local and
+// file:// vaults, and hosts yoink doesn't recognise, use plain git
+func ForgeFor(repoURL string) (Forge, error) {
+	cfg := forgeConfigs[repoURL]
+	remote := ParseRemote(repoURL)
+
+	kind := cfg.Kind
+	if kind == "" {
+		kind = detectForge(remote)
+	}
+
+	switch kind {
+	case ForgeGitHub:
+		return newGitHubForge(repoURL)
+	case ForgeGitLab:
+		return gitlabForge{}, nil
+	case ForgeGitea:
+		return newGiteaForge(remote, cfg.URL)
+	case ForgePlain:
+		return plainForge{}, nil
+	default:
+		return nil, fmt.Errorf("unknown forge %q (expected github, gitlab, gitea or git)", kind)
+	}
+}
+
+func detectForge(remote Remote) string {
+	host := strings.ToLower(remote.Host)
+	switch {
+	case host == "":
+		return ForgePlain
+	case strings.Contains(host, "github"):
+		return ForgeGitHub
+	case strings.Contains(host, "gitlab"):
+		return ForgeGitLab
+	case strings.Contains(host, "gitea"), host == "codeberg.org":
+		return ForgeGitea
+	default:
+		return ForgePlain
+	}
+}
+
+// pushBranch pushes a branch to origin with optional push options
+func pushBranch(repoDir, branch string, options ...string) (string, error) {
+	args := []string{"-C", repoDir, "push"}
+	for _, o := range options {
+		args = append(args, "-o", o)
+	}
+	args = append(args, "origin", branch)
+
+	output, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		return string(output), fmt.Errorf("failed to push branch: %w\nOutput: %s", err, strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
+
+// plainForge pushes the branch and leaves merging it to the team, for bare
+// repositories and servers without pull requests
+type plainForge struct{}
+
+func (plainForge) Name() string { return ForgePlain }
+
+func (plainForge) Propose(repoDir string, pr PullRequest) (string, error) {
+	_, err := pushBranch(repoDir, pr.Branch)
+	return "", err
+}
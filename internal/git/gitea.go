@@ -0,0 +1,77 @@
+package git
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/jack-kitto/yoink/internal/util"
+)
+
+// giteaForge opens pull requests through the Gitea API, authenticated with
+// GITEA_TOKEN
+type giteaForge struct {
+	baseURL string
+	repo    string
+}
+
+func newGiteaForge(remote Remote, baseURL string) (Forge, error) {
+	if remote.Local() {
+		return nil, fmt.Errorf("a Gitea vault needs its remote URL, not a local path")
+	}
+	if baseURL == "" {
+		baseURL = "https://" + remote.Host
+	}
+	return giteaForge{baseURL: strings.TrimSuffix(baseURL, "/"), repo: remote.Path}, nil
+}
+
+func (giteaForge) Name() string { return ForgeGitea }
+
+func (f giteaForge) Propose(repoDir string, pr PullRequest) (string, error) {
+	token := os.Getenv("GITEA_TOKEN")
+	if token == "" {
+		return "", fmt.Errorf("set GITEA_TOKEN to open pull requests on %s", f.baseURL)
+	}
+
+	if _, err := pushBranch(repoDir, pr.Branch); err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		"head":  pr.Branch,
+		"base":  pr.Base,
+		"title": pr.Title,
+		"body":  pr.Body,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/api/v1/repos/%s/pulls", f.baseURL, f.repo), bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "token "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := util.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to create PR: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("failed to create PR: %s returned %s", f.baseURL, resp.Status)
+	}
+
+	var created struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return "", nil
+	}
+	return created.HTMLURL, nil
+}
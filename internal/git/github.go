@@ -6,7 +6,9 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/jack-kitto/yoink/internal/github"
 	"github.com/jack-kitto/yoink/internal/util"
 )
 
@@ -75,102 +77,157 @@ func CommitVault(vaultURL, secretsFile, msg string, dryRun bool) error {
 	return nil
 }
 
-func CreatePR(repoURL, title, body, branch string) error {
-	// Extract repository name from URL
-	repoName := extractRepoFromURL(repoURL)
-	if repoName == "" {
-		return fmt.Errorf("invalid repository URL: %s", repoURL)
+// GitHubClient returns an API client for a GitHub vault and its owner/repo,
+// using the API of the forge_url configured for it on GitHub Enterprise
+func GitHubClient(repoURL string) (*github.Client, string, error) {
+	remote := ParseRemote(repoURL)
+	cfg := forgeConfigs[repoURL]
+	kind := cfg.Kind
+	if kind == "" {
+		kind = detectForge(remote)
+	}
+	if kind != ForgeGitHub || remote.Local() {
+		return nil, "", fmt.Errorf("%s is not a GitHub repository", repoURL)
 	}
 
-	// Create PR using gh CLI
-	cmd := exec.Command("gh", "pr", "create",
-		"--repo", repoName,
-		"--title", title,
-		"--body", body,
-		"--head", branch,
-	)
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to create PR: %w", err)
+	apiURL := ""
+	if cfg.URL != "" {
+		apiURL = strings.TrimSuffix(cfg.URL, "/")
+		if !strings.Contains(apiURL, "/api/") {
+			apiURL += "/api/v3"
+		}
 	}
+	return github.New(remote.Host, apiURL), remote.Path, nil
+}
 
-	return nil
+// githubForge opens pull requests through the GitHub API
+type githubForge struct {
+	repoURL string
 }
 
-func ForkAndCreatePR(repoURL, title, body string) error {
-	// Extract repository name from URL
-	repoName := extractRepoFromURL(repoURL)
-	if repoName == "" {
-		return fmt.Errorf("invalid repository URL: %s", repoURL)
+func newGitHubForge(repoURL string) (Forge, error) {
+	if ParseRemote(repoURL).Local() {
+		return nil, fmt.Errorf("a GitHub vault needs its remote URL, not a local path")
 	}
+	return githubForge{repoURL: repoURL}, nil
+}
+
+func (githubForge) Name() string { return ForgeGitHub }
 
-	// Fork the repository
-	forkCmd := exec.Command("gh", "repo", "fork", repoName, "--clone=false")
-	if err := forkCmd.Run(); err != nil {
-		// Fork might already exist, continue
-		fmt.Printf("⚠️  Fork might already exist: %v\n", err)
+func (f githubForge) Propose(repoDir string, pr PullRequest) (string, error) {
+	client, repoName, err := GitHubClient(f.repoURL)
+	if err != nil {
+		return "", err
+	}
+	if client.Token == "" {
+		return "", fmt.Errorf("no GitHub token found - set GH_TOKEN or run 'gh auth login'")
 	}
 
-	// Create PR
-	prCmd := exec.Command("gh", "pr", "create",
-		"--repo", repoName,
-		"--title", title,
-		"--body", body,
-	)
+	if _, err := pushBranch(repoDir, pr.Branch); err != nil {
+		return "", err
+	}
 
-	output, err := prCmd.CombinedOutput()
+	created, err := client.CreatePullRequest(repoName, github.NewPullRequest{
+		Title: pr.Title,
+		Head:  pr.Branch,
+		Base:  pr.Base,
+		Body:  pr.Body,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to create PR: %w\nOutput: %s", err, string(output))
+		return "", fmt.Errorf("failed to create PR: %w", err)
 	}
-
-	fmt.Printf("✅ Pull request created: %s\n", string(output))
-	return nil
+	return created.HTMLURL, nil
 }
 
-func CheckRepoExists(repoURL string) error {
-	repoName := extractRepoFromURL(repoURL)
-	if repoName == "" {
-		return fmt.Errorf("invalid repository URL: %s", repoURL)
+// ForkAndCreatePR proposes adding a file to a GitHub repository without a
+// local clone. It commits the file to a new branch, in a fork unless the
+// user can push to the repository, and opens a pull request for it
+func ForkAndCreatePR(repoURL, file string, content []byte, title, body string) (string, error) {
+	client, repoName, err := GitHubClient(repoURL)
+	if err != nil {
+		return "", err
 	}
 
-	cmd := exec.Command("gh", "repo", "view", repoName)
-	return cmd.Run()
-}
+	upstream, err := client.Repo(repoName)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up %s: %w", repoName, err)
+	}
+	head, err := client.BranchHead(repoName, upstream.DefaultBranch)
+	if err != nil {
+		return "", err
+	}
 
-func CreateRepo(repoURL, description string, private bool) error {
-	repoName := extractRepoFromURL(repoURL)
-	if repoName == "" {
-		return fmt.Errorf("invalid repository URL: %s", repoURL)
+	target := upstream.FullName
+	forked := !upstream.Permissions.Push
+	if forked {
+		fork, err := client.Fork(repoName)
+		if err != nil {
+			return "", fmt.Errorf("failed to fork %s: %w", repoName, err)
+		}
+		target = fork.FullName
 	}
 
-	args := []string{"repo", "create", repoName}
-	if description != "" {
-		args = append(args, "--description", description)
+	branch := fmt.Sprintf("yoink-onboard-%d", time.Now().Unix())
+
+	// A new fork takes a few seconds to become usable
+	for attempt := 0; ; attempt++ {
+		err = client.CreateBranch(target, branch, head)
+		if err == nil || !forked || attempt == 10 {
+			break
+		}
+		time.Sleep(2 * time.Second)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to create branch in %s: %w", target, err)
 	}
-	if private {
-		args = append(args, "--private")
+
+	if err := client.PutFile(target, branch, file, title, content); err != nil {
+		return "", fmt.Errorf("failed to commit %s: %w", file, err)
 	}
 
-	cmd := exec.Command("gh", args...)
-	return cmd.Run()
+	prHead := branch
+	if forked {
+		prHead = strings.Split(target, "/")[0] + ":" + branch
+	}
+	pr, err := client.CreatePullRequest(repoName, github.NewPullRequest{
+		Title: title,
+		Head:  prHead,
+		Base:  upstream.DefaultBranch,
+		Body:  body,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create PR: %w", err)
+	}
+	return pr.HTMLURL, nil
 }
 
-func extractRepoFromURL(repoURL string) string {
-	// Handle SSH and HTTPS URLs
-	if strings.Contains(repoURL, "github.com:") {
-		// SSH: git@github.com:user/repo.git
-		parts := strings.Split(repoURL, ":")
-		if len(parts) >= 2 {
-			return strings.TrimSuffix(parts[1], ".git")
-		}
-	} else if strings.Contains(repoURL, "github.com/") {
-		// HTTPS: https://github.com/user/repo.git
-		parts := strings.Split(repoURL, "github.com/")
-		if len(parts) >= 2 {
-			return strings.TrimSuffix(parts[1], ".git")
-		}
+// CheckRepoExists returns an error unless a GitHub repository exists and can
+// be seen with the user's token
+func CheckRepoExists(repoURL string) error {
+	client, repoName, err := GitHubClient(repoURL)
+	if err != nil {
+		return err
 	}
-	return ""
+
+	exists, err := client.RepoExists(repoName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("repository %s not found", repoName)
+	}
+	return nil
+}
+
+// CreateRepo creates a GitHub repository
+func CreateRepo(repoURL, description string, private bool) error {
+	client, repoName, err := GitHubClient(repoURL)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.CreateRepo(repoName, description, private)
+	return err
 }
 
 func GetCurrentBranch() (string, error) {
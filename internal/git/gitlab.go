@@ -0,0 +1,35 @@
+package git
+
+import (
+	"regexp"
+	"strings"
+)
+
+var mergeRequestURL = regexp.MustCompile(`https?://\S+/-/merge_requests/\d+`)
+
+// gitlabForge opens merge requests with GitLab push options, so no API token
+// is needed beyond the credentials used to push
+type gitlabForge struct{}
+
+func (gitlabForge) Name() string { return ForgeGitLab }
+
+func (gitlabForge) Propose(repoDir string, pr PullRequest) (string, error) {
+	output, err := pushBranch(repoDir, pr.Branch,
+		"merge_request.create",
+		"merge_request.target="+pr.Base,
+		"merge_request.title="+pushOption(pr.Title),
+		"merge_request.description="+pushOption(pr.Body),
+		"merge_request.remove_source_branch",
+	)
+	if err != nil {
+		return "", err
+	}
+
+	// GitLab reports the merge request in the push's remote messages
+	return mergeRequestURL.FindString(output), nil
+}
+
+// pushOption flattens a value onto one line, as push options can't span lines
+func pushOption(v string) string {
+	return strings.Join(strings.Fields(v), " ")
+}
@@ -0,0 +1,283 @@
+package github
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// User is a GitHub account
+type User struct {
+	Login string `json:"login"`
+}
+
+// Repository is a GitHub repository
+type Repository struct {
+	FullName      string `json:"full_name"`
+	Owner         User   `json:"owner"`
+	Private       bool   `json:"private"`
+	DefaultBranch string `json:"default_branch"`
+	HTMLURL       string `json:"html_url"`
+	CloneURL      string `json:"clone_url"`
+	SSHURL        string `json:"ssh_url"`
+	Permissions   struct {
+		Push bool `json:"push"`
+	} `json:"permissions"`
+}
+
+// Commit is an entry in a repository's history
+type Commit struct {
+	SHA    string `json:"sha"`
+	Commit struct {
+		Message string `json:"message"`
+		Author  struct {
+			Name string    `json:"name"`
+			Date time.Time `json:"date"`
+		} `json:"author"`
+	} `json:"commit"`
+}
+
+// PullRequest is an open or closed pull request
+type PullRequest struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	HTMLURL string `json:"html_url"`
+	User    User   `json:"user"`
+}
+
+// NewPullRequest describes a pull request to open. Head is a branch of the
+// same repository, or owner:branch for a branch of a fork
+type NewPullRequest struct {
+	Title string `json:"title"`
+	Head  string `json:"head"`
+	Base  string `json:"base"`
+	Body  string `json:"body"`
+}
+
+// CurrentUser returns the login of the token's owner
+func (c *Client) CurrentUser() (string, error) {
+	if c.Token == "" {
+		return "", fmt.Errorf("no GitHub token found - set GH_TOKEN or run 'gh auth login'")
+	}
+
+	var user User
+	if err := c.get("/user", &user); err != nil {
+		return "", err
+	}
+	if user.Login == "" {
+		return "", fmt.Errorf("could not determine GitHub username")
+	}
+	return user.Login, nil
+}
+
+// Repo returns a repository by owner/name
+func (c *Client) Repo(repo string) (*Repository, error) {
+	var r Repository
+	if err := c.get("/repos/"+repo, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// RepoExists reports whether the repository exists and the token can see it
+func (c *Client) RepoExists(repo string) (bool, error) {
+	_, err := c.Repo(repo)
+	if IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// CreateRepo creates owner/name, in the token owner's account or in the
+// organisation owner
+func (c *Client) CreateRepo(repo, description string, private bool) (*Repository, error) {
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid repository %q (expected owner/name)", repo)
+	}
+
+	login, err := c.CurrentUser()
+	if err != nil {
+		return nil, err
+	}
+	path := "/user/repos"
+	if !strings.EqualFold(owner, login) {
+		path = "/orgs/" + owner + "/repos"
+	}
+
+	var r Repository
+	err = c.send(http.MethodPost, path, map[string]interface{}{
+		"name":        name,
+		"description": description,
+		"private":     private,
+	}, &r)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Fork forks a repository into the token owner's account, returning the
+// fork. GitHub creates forks in the background, so it may take a few
+// seconds before its branches can be used
+func (c *Client) Fork(repo string) (*Repository, error) {
+	var r Repository
+	if err := c.send(http.MethodPost, "/repos/"+repo+"/forks", map[string]interface{}{}, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Commits returns up to limit commits of the default branch, newest first;
+// a limit of 0 returns them all
+func (c *Client) Commits(repo string, limit int) ([]Commit, error) {
+	var commits []Commit
+	err := c.list(fmt.Sprintf("/repos/%s/commits?per_page=%d", repo, perPage(limit)), func(items json.RawMessage) (bool, error) {
+		var page []Commit
+		if err := json.Unmarshal(items, &page); err != nil {
+			return false, err
+		}
+		commits = append(commits, page...)
+		return limit <= 0 || len(commits) < limit, nil
+	})
+	if limit > 0 && len(commits) > limit {
+		commits = commits[:limit]
+	}
+	return commits, err
+}
+
+// PullRequests returns the repository's open pull requests, oldest first
+func (c *Client) PullRequests(repo string) ([]PullRequest, error) {
+	var prs []PullRequest
+	err := c.list(fmt.Sprintf("/repos/%s/pulls?state=open&per_page=100", repo), func(items json.RawMessage) (bool, error) {
+		var page []PullRequest
+		if err := json.Unmarshal(items, &page); err != nil {
+			return false, err
+		}
+		prs = append(prs, page...)
+		return true, nil
+	})
+	return prs, err
+}
+
+// CreatePullRequest opens a pull request on repo
+func (c *Client) CreatePullRequest(repo string, pr NewPullRequest) (*PullRequest, error) {
+	var created PullRequest
+	if err := c.send(http.MethodPost, "/repos/"+repo+"/pulls", pr, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// Contents returns the raw contents of a file at ref, which may be a branch,
+// tag or commit SHA; an empty ref means the default branch
+func (c *Client) Contents(repo, path, ref string) ([]byte, error) {
+	p := fmt.Sprintf("/repos/%s/contents/%s", repo, escapePath(path))
+	if ref != "" {
+		p += "?ref=" + url.QueryEscape(ref)
+	}
+
+	resp, err := c.do(http.MethodGet, p, nil, http.Header{"Accept": {"application/vnd.github.raw+json"}})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// File returns the raw contents of a file at ref (a branch, tag or commit
+// SHA; empty for the default branch). Anonymous clients of github.com read
+// public files from raw.githubusercontent.com, which has no API rate limit;
+// everything else goes through the authenticated contents API
+func (c *Client) File(repo, path, ref string) ([]byte, error) {
+	if c.Token != "" || c.BaseURL != APIURL(DefaultHost) {
+		return c.Contents(repo, path, ref)
+	}
+
+	if ref == "" {
+		ref = "HEAD"
+	}
+	rawURL := fmt.Sprintf("https://raw.githubusercontent.com/%s/%s/%s", repo, url.PathEscape(ref), escapePath(path))
+	resp, err := c.httpClient().Get(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Private repositories look missing without a token
+		return nil, fmt.Errorf("%s not found at %s in %s (private vaults need GH_TOKEN or 'gh auth login')", path, ref, repo)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// CommitSHA returns the commit a ref (empty for the default branch) points
+// at. Passing the ETag of an earlier answer makes the request conditional:
+// if the ref hasn't moved it returns notModified, and GitHub doesn't count
+// the request against the rate limit
+func (c *Client) CommitSHA(repo, ref, etag string) (sha, newETag string, notModified bool, err error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	header := http.Header{"Accept": {"application/vnd.github.sha"}}
+	if etag != "" {
+		header.Set("If-None-Match", etag)
+	}
+
+	resp, err := c.do(http.MethodGet, fmt.Sprintf("/repos/%s/commits/%s", repo, url.PathEscape(ref)), nil, header)
+	if err != nil {
+		return "", "", false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return "", etag, true, nil
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+	if err != nil {
+		return "", "", false, err
+	}
+	return strings.TrimSpace(string(data)), resp.Header.Get("ETag"), false, nil
+}
+
+// BranchHead returns the commit a branch points at
+func (c *Client) BranchHead(repo, branch string) (string, error) {
+	var ref struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+	if err := c.get(fmt.Sprintf("/repos/%s/git/ref/heads/%s", repo, escapePath(branch)), &ref); err != nil {
+		return "", err
+	}
+	return ref.Object.SHA, nil
+}
+
+// CreateBranch creates a branch pointing at a commit
+func (c *Client) CreateBranch(repo, branch, sha string) error {
+	return c.send(http.MethodPost, "/repos/"+repo+"/git/refs", map[string]string{
+		"ref": "refs/heads/" + branch,
+		"sha": sha,
+	}, nil)
+}
+
+// PutFile commits a new file to a branch
+func (c *Client) PutFile(repo, branch, path, message string, content []byte) error {
+	return c.send(http.MethodPut, fmt.Sprintf("/repos/%s/contents/%s", repo, escapePath(path)), map[string]string{
+		"message": message,
+		"content": base64.StdEncoding.EncodeToString(content),
+		"branch":  branch,
+	}, nil)
+}
+
+func perPage(limit int) int {
+	if limit <= 0 || limit > 100 {
+		return 100
+	}
+	return limit
+}
@@ -0,0 +1,324 @@
+package github
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jack-kitto/yoink/internal/util"
+)
+
+// DefaultHost is the host used when no other is configured
+const DefaultHost = "github.com"
+
+// maxRetries bounds how often a rate-limited request is retried
+const maxRetries = 3
+
+// Client talks to the GitHub REST API of github.com or a GitHub Enterprise
+// Server. Point BaseURL at an httptest server to exercise it in tests
+type Client struct {
+	// BaseURL is the API root: https://api.github.com for github.com and
+	// https://HOST/api/v3 for GitHub Enterprise Server
+	BaseURL string
+	// Host is the web host repositories live on
+	Host string
+	// Token authenticates requests; anonymous requests only see public
+	// repositories and have a much lower rate limit
+	Token string
+	// HTTPClient sends the requests
+	HTTPClient *http.Client
+	// MaxWait is the longest a request waits for a rate limit to reset
+	// before giving up
+	MaxWait time.Duration
+}
+
+// Error is a failed API request
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("GitHub API returned %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("GitHub API returned %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is a 404 from the API, which GitHub also
+// returns for private repositories the token can't see
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// New returns a client for host (GH_HOST or github.com when empty),
+// authenticated with the token discovered for it. apiURL overrides the API
+// root; GITHUB_API_URL does the same when apiURL is empty
+func New(host, apiURL string) *Client {
+	if host == "" {
+		host = os.Getenv("GH_HOST")
+	}
+	if host == "" {
+		host = DefaultHost
+	}
+	if apiURL == "" {
+		apiURL = os.Getenv("GITHUB_API_URL")
+	}
+	if apiURL == "" {
+		apiURL = APIURL(host)
+	}
+
+	return &Client{
+		BaseURL:    strings.TrimSuffix(apiURL, "/"),
+		Host:       host,
+		Token:      Token(host),
+		HTTPClient: util.HTTPClient,
+		MaxWait:    time.Minute,
+	}
+}
+
+// APIURL returns the API root for a web host
+func APIURL(host string) string {
+	if strings.EqualFold(host, DefaultHost) || strings.EqualFold(host, "www."+DefaultHost) {
+		return "https://api.github.com"
+	}
+	return "https://" + host + "/api/v3"
+}
+
+// get decodes the JSON response to a GET into out
+func (c *Client) get(path string, out interface{}) error {
+	resp, err := c.do(http.MethodGet, path, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decode(resp, out)
+}
+
+// send makes a request with a JSON body and decodes the response into out,
+// when out is non-nil
+func (c *Client) send(method, path string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(method, path, payload, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decode(resp, out)
+}
+
+var nextLink = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// list follows the pagination of a list endpoint, calling page with each
+// page's items until it returns false or the pages run out
+func (c *Client) list(path string, page func(items json.RawMessage) (bool, error)) error {
+	for path != "" {
+		resp, err := c.do(http.MethodGet, path, nil, nil)
+		if err != nil {
+			return err
+		}
+
+		var items json.RawMessage
+		err = decode(resp, &items)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		more, err := page(items)
+		if err != nil || !more {
+			return err
+		}
+
+		path = ""
+		if m := nextLink.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+			if !c.sameOrigin(m[1]) {
+				return fmt.Errorf("GitHub API pagination points at another host: %s", m[1])
+			}
+			path = m[1]
+		}
+	}
+	return nil
+}
+
+// sameOrigin reports whether an absolute URL has the scheme and host of
+// BaseURL, the only place the token may be sent
+func (c *Client) sameOrigin(target string) bool {
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	base, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return false
+	}
+	return u.Scheme == base.Scheme && strings.EqualFold(u.Host, base.Host)
+}
+
+// do sends a request to path, relative to BaseURL unless it is a full URL,
+// waiting out rate limits that reset within MaxWait. The caller closes the
+// response body of successful requests
+func (c *Client) do(method, path string, body []byte, header http.Header) (*http.Response, error) {
+	target := path
+	if !strings.HasPrefix(path, "https://") && !strings.HasPrefix(path, "http://") {
+		target = c.BaseURL + "/" + strings.TrimPrefix(path, "/")
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(method, target, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if c.Token != "" && c.sameOrigin(target) {
+			req.Header.Set("Authorization", "Bearer "+c.Token)
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+
+		resp, err := c.httpClient().Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode < 400 {
+			return resp, nil
+		}
+
+		wait, limited := rateLimitWait(resp)
+		if limited && attempt < maxRetries && wait <= c.MaxWait {
+			resp.Body.Close()
+			time.Sleep(wait)
+			continue
+		}
+
+		err = responseError(resp)
+		resp.Body.Close()
+		if limited {
+			err = c.rateLimitError(resp, err)
+		}
+		return nil, err
+	}
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return util.HTTPClient
+}
+
+// rateLimitWait reports whether resp was rejected by a primary or secondary
+// rate limit, and how long until the request may be retried
+func rateLimitWait(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	if after := resp.Header.Get("Retry-After"); after != "" {
+		if secs, err := strconv.Atoi(after); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+		if err != nil {
+			return 0, true
+		}
+		wait := time.Until(time.Unix(reset, 0)) + time.Second
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+
+	// Secondary limits without Retry-After ask for at least a minute
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return time.Minute, true
+	}
+	return 0, false
+}
+
+func (c *Client) rateLimitError(resp *http.Response, err error) error {
+	msg := "GitHub rate limit exceeded"
+	if reset, perr := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); perr == nil {
+		msg += " until " + time.Unix(reset, 0).Local().Format("15:04")
+	}
+	if c.Token == "" {
+		msg += " - set GH_TOKEN for a higher limit"
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+func responseError(resp *http.Response) error {
+	var body struct {
+		Message string `json:"message"`
+		Errors  []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	json.Unmarshal(data, &body)
+
+	msg := body.Message
+	for _, e := range body.Errors {
+		if e.Message != "" {
+			msg += " (" + e.Message + ")"
+		}
+	}
+	return &Error{StatusCode: resp.StatusCode, Message: msg}
+}
+
+func decode(resp *http.Response, out interface{}) error {
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("unexpected response from GitHub API: %w", err)
+	}
+	return nil
+}
+
+// escapePath escapes each segment of a repository file path
+func escapePath(p string) string {
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+// GitEnv returns environment variables that let git commands authenticate
+// to Host over HTTPS with the client's token, without writing it to disk or
+// putting it on a command line
+func (c *Client) GitEnv() []string {
+	if c.Token == "" {
+		return nil
+	}
+	auth := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + c.Token))
+	return []string{
+		"GIT_CONFIG_COUNT=1",
+		fmt.Sprintf("GIT_CONFIG_KEY_0=http.https://%s/.extraheader", c.Host),
+		"GIT_CONFIG_VALUE_0=AUTHORIZATION: basic " + auth,
+	}
+}
@@ -0,0 +1,187 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client for an httptest stand-in of the API
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return &Client{BaseURL: srv.URL, Host: "github.example.com", Token: "test-token", HTTPClient: srv.Client(), MaxWait: time.Second}
+}
+
+func TestListFollowsLinkHeader(t *testing.T) {
+	var srvURL string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer test-token" {
+			t.Errorf("Authorization = %q", got)
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		if page < 3 {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/o/r/pulls?page=%d>; rel="next", <%s/repos/o/r/pulls?page=3>; rel="last"`, srvURL, page+1, srvURL))
+		}
+		fmt.Fprintf(w, `[{"number": %d}, {"number": %d}]`, page*2-1, page*2)
+	})
+	srvURL = c.BaseURL
+
+	prs, err := c.PullRequests("o/r")
+	if err != nil {
+		t.Fatalf("PullRequests: %v", err)
+	}
+	if len(prs) != 6 || prs[0].Number != 1 || prs[5].Number != 6 {
+		t.Fatalf("got %+v, want pull requests 1-6 across three pages", prs)
+	}
+
+	// A limit stops paging early
+	commits := 0
+	c2 := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		commits++
+		w.Header().Set("Link", fmt.Sprintf(`<%s/next>; rel="next"`, srvURL))
+		fmt.Fprint(w, `[{"sha": "a"}, {"sha": "b"}]`)
+	})
+	srvURL = c2.BaseURL
+	got, err := c2.Commits("o/r", 3)
+	if err != nil {
+		t.Fatalf("Commits: %v", err)
+	}
+	if len(got) != 3 || commits != 2 {
+		t.Fatalf("got %d commits from %d requests, want 3 from 2", len(got), commits)
+	}
+
+	// A next link to another host must not get the token
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "" {
+			t.Errorf("other host received Authorization = %q", got)
+		}
+		fmt.Fprint(w, `[{"number": 99}]`)
+	}))
+	defer other.Close()
+	c3 := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", fmt.Sprintf(`<%s/repos/o/r/pulls?page=2>; rel="next"`, other.URL))
+		fmt.Fprint(w, `[{"number": 1}]`)
+	})
+	if _, err := c3.PullRequests("o/r"); err == nil || !strings.Contains(err.Error(), "another host") {
+		t.Fatalf("err = %v, want pagination to another host refused", err)
+	}
+	if _, err := c3.do(http.MethodGet, other.URL+"/user", nil, nil); err != nil {
+		t.Fatalf("request to other host: %v", err)
+	}
+}
+
+func TestDoRetriesRateLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		limited func(w http.ResponseWriter)
+	}{
+		{"retry-after", func(w http.ResponseWriter) {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}},
+		{"primary limit reset", func(w http.ResponseWriter) {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(-5*time.Second).Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message": "API rate limit exceeded"}`)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls < 3 {
+					tt.limited(w)
+					return
+				}
+				fmt.Fprint(w, `{"login": "octocat"}`)
+			})
+
+			login, err := c.CurrentUser()
+			if err != nil {
+				t.Fatalf("CurrentUser: %v", err)
+			}
+			if login != "octocat" || calls != 3 {
+				t.Fatalf("got %q after %d calls, want octocat after 3", login, calls)
+			}
+		})
+	}
+}
+
+func TestDoGivesUpOnLongRateLimits(t *testing.T) {
+	calls := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message": "API rate limit exceeded"}`)
+	})
+
+	_, err := c.Repo("o/r")
+	if err == nil || !strings.Contains(err.Error(), "rate limit exceeded until") {
+		t.Fatalf("err = %v, want a rate limit error", err)
+	}
+	if calls != 1 {
+		t.Fatalf("made %d calls, want 1 when the reset is beyond MaxWait", calls)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "Not Found"}`)
+	})
+
+	_, err := c.Repo("o/missing")
+	if !IsNotFound(err) {
+		t.Fatalf("IsNotFound(%v) = false", err)
+	}
+	if IsNotFound(fmt.Errorf("other: %w", &Error{StatusCode: http.StatusForbidden})) {
+		t.Fatalf("IsNotFound reported a 403")
+	}
+
+	exists, err := c.RepoExists("o/missing")
+	if exists || err != nil {
+		t.Fatalf("RepoExists = %v, %v; want false, nil", exists, err)
+	}
+}
+
+func TestTokenSelection(t *testing.T) {
+	// Keep gh's config and binary out of the lookup
+	t.Setenv("GH_CONFIG_DIR", t.TempDir())
+	t.Setenv("PATH", "")
+	for _, v := range []string{"GH_TOKEN", "GITHUB_TOKEN", "GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"} {
+		t.Setenv(v, "")
+	}
+
+	t.Setenv("GITHUB_TOKEN", "dotcom-fallback")
+	t.Setenv("GH_ENTERPRISE_TOKEN", "enterprise")
+	if got := Token(DefaultHost); got != "dotcom-fallback" {
+		t.Errorf("Token(github.com) = %q, want GITHUB_TOKEN", got)
+	}
+
+	t.Setenv("GH_TOKEN", "dotcom")
+	if got := Token(DefaultHost); got != "dotcom" {
+		t.Errorf("Token(github.com) = %q, want GH_TOKEN to win over GITHUB_TOKEN", got)
+	}
+	if got := Token("github.example.com"); got != "enterprise" {
+		t.Errorf("Token(enterprise host) = %q, want GH_ENTERPRISE_TOKEN", got)
+	}
+
+	t.Setenv("GH_ENTERPRISE_TOKEN", "")
+	if got := Token("github.example.com"); got != "" {
+		t.Errorf("Token(enterprise host) = %q, want no github.com token to leak to it", got)
+	}
+}
@@ -0,0 +1,80 @@
+package github
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ghHost is a host entry in the gh CLI's hosts.yml
+type ghHost struct {
+	OAuthToken string `yaml:"oauth_token"`
+}
+
+// Token finds a token for host the way the gh CLI does: GH_TOKEN or
+// GITHUB_TOKEN for github.com, GH_ENTERPRISE_TOKEN or GITHUB_ENTERPRISE_TOKEN
+// for other hosts, then gh's hosts.yml. When gh keeps its token in the
+// system keyring and is installed, it is asked for it. Returns "" when no
+// token is found
+func Token(host string) string {
+	vars := []string{"GH_TOKEN", "GITHUB_TOKEN"}
+	if !strings.EqualFold(host, DefaultHost) {
+		vars = []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}
+	}
+	for _, v := range vars {
+		if token := os.Getenv(v); token != "" {
+			return token
+		}
+	}
+
+	if token := hostsFileToken(host); token != "" {
+		return token
+	}
+
+	if _, err := exec.LookPath("gh"); err == nil {
+		out, err := exec.Command("gh", "auth", "token", "--hostname", host).Output()
+		if err == nil {
+			return strings.TrimSpace(string(out))
+		}
+	}
+	return ""
+}
+
+func hostsFileToken(host string) string {
+	data, err := os.ReadFile(filepath.Join(ghConfigDir(), "hosts.yml"))
+	if err != nil {
+		return ""
+	}
+
+	var hosts map[string]ghHost
+	if err := yaml.Unmarshal(data, &hosts); err != nil {
+		return ""
+	}
+	for name, h := range hosts {
+		if strings.EqualFold(name, host) {
+			return h.OAuthToken
+		}
+	}
+	return ""
+}
+
+// ghConfigDir mirrors where the gh CLI keeps its configuration
+func ghConfigDir() string {
+	if dir := os.Getenv("GH_CONFIG_DIR"); dir != "" {
+		return dir
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh")
+	}
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("AppData"); dir != "" {
+			return filepath.Join(dir, "GitHub CLI")
+		}
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "gh")
+}
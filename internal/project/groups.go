@@ -0,0 +1,198 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GroupsFile is the vault file recording named groups of recipients
+const GroupsFile = "groups.yaml"
+
+type GroupsConfig struct {
+	Groups map[string]*Group `yaml:"groups"`
+}
+
+// Group is a named team of recipients. A group without envs can decrypt
+// every environment.
+type Group struct {
+	Envs    []string `yaml:"envs,omitempty"`
+	Members []Member `yaml:"members"`
+}
+
+type Member struct {
+	Name string `yaml:"name"`
+	Key  string `yaml:"key"`
+}
+
+// LoadGroups reads the groups file, returning an empty config if it doesn't exist
+func LoadGroups(path string) (GroupsConfig, error) {
+	c := GroupsConfig{Groups: map[string]*Group{}}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if c.Groups == nil {
+		c.Groups = map[string]*Group{}
+	}
+	return c, nil
+}
+
+// Save writes the groups file to path
+func (c GroupsConfig) Save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Names returns the sorted group names
+func (c GroupsConfig) Names() []string {
+	names := make([]string, 0, len(c.Groups))
+	for name := range c.Groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Member returns the index of a named member, or -1
+func (g *Group) Member(name string) int {
+	for i, m := range g.Members {
+		if m.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Covers reports whether the group has access to an environment; "" is the
+// default rule covering files outside any environment
+func (g *Group) Covers(env string) bool {
+	if len(g.Envs) == 0 {
+		return true
+	}
+	for _, e := range g.Envs {
+		if e == env {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveEnv takes a deleted environment out of every group, reporting
+// whether anything changed. Groups left without environments would cover
+// every environment, so they are removed instead and their names returned
+func (c GroupsConfig) RemoveEnv(env string) (changed bool, removed []string) {
+	for _, name := range c.Names() {
+		g := c.Groups[name]
+		envs := make([]string, 0, len(g.Envs))
+		for _, e := range g.Envs {
+			if e != env {
+				envs = append(envs, e)
+			}
+		}
+		if len(envs) == len(g.Envs) {
+			continue
+		}
+		changed = true
+		if len(envs) == 0 {
+			delete(c.Groups, name)
+			removed = append(removed, name)
+			continue
+		}
+		g.Envs = envs
+	}
+	return changed, removed
+}
+
+// Grants returns the keys group membership gives each creation rule, by
+// path_regex
+func (c GroupsConfig) Grants(sopsCfg SOPSConfig) map[string]map[string]bool {
+	grants := make(map[string]map[string]bool, len(sopsCfg.CreationRules))
+	for _, rule := range sopsCfg.CreationRules {
+		env := ruleEnv(rule.PathRegex)
+		keys := map[string]bool{}
+		for _, name := range c.Names() {
+			g := c.Groups[name]
+			if !g.Covers(env) {
+				continue
+			}
+			for _, m := range g.Members {
+				keys[m.Key] = true
+			}
+		}
+		grants[rule.PathRegex] = keys
+	}
+	return grants
+}
+
+// ApplyTo regenerates the recipients of every creation rule from group
+// membership. previous holds the keys the groups gave each rule before the
+// change, as returned by Grants: those are replaced by the current members,
+// and keys a rule had beyond them, added with 'access grant', are kept. A key
+// granted to a rule both directly and through a group can't be told apart
+// from a group grant, so it goes when the group no longer gives it.
+func (c GroupsConfig) ApplyTo(sopsCfg *SOPSConfig, previous map[string]map[string]bool) {
+	granted := make(map[string]map[string]bool, len(previous))
+	for regex, keys := range previous {
+		granted[regex] = keys
+	}
+
+	// New environment rules start from the recipients of the rule covering
+	// the environment, group grants included
+	for _, name := range c.Names() {
+		for _, env := range c.Groups[name].Envs {
+			var from map[string]bool
+			if current := sopsCfg.RuleFor(EnvsDir + "/" + env + "/secrets.enc.yaml"); current != nil {
+				from = granted[current.PathRegex]
+			}
+			rule := sopsCfg.EnvRule(env)
+			if _, ok := granted[rule.PathRegex]; !ok {
+				granted[rule.PathRegex] = from
+			}
+		}
+	}
+
+	for i := range sopsCfg.CreationRules {
+		rule := &sopsCfg.CreationRules[i]
+		env := ruleEnv(rule.PathRegex)
+		before := granted[rule.PathRegex]
+
+		age, pgp := KeyList{}, KeyList{}
+		for _, k := range rule.Age {
+			if !before[k] {
+				age = append(age, k)
+			}
+		}
+		for _, k := range rule.PGP {
+			if !before[k] {
+				pgp = append(pgp, k)
+			}
+		}
+		rule.Age, rule.PGP = age, pgp
+
+		for _, name := range c.Names() {
+			g := c.Groups[name]
+			if !g.Covers(env) {
+				continue
+			}
+			for _, m := range g.Members {
+				if list := rule.KeysFor(m.Key); !list.Contains(m.Key) {
+					*list = append(*list, m.Key)
+				}
+			}
+		}
+	}
+}
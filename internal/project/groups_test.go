@@ -0,0 +1,55 @@
+package project
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyToKeepsDirectGrants(t *testing.T) {
+	const alice, bob, carol = "age1alice", "age1bob", "age1carol"
+
+	// bob holds prod through the devs group and staging directly
+	groups := GroupsConfig{Groups: map[string]*Group{
+		"devs": {Envs: []string{"prod"}, Members: []Member{{Name: "bob", Key: bob}}},
+	}}
+	sopsCfg := SOPSConfig{CreationRules: []CreationRule{
+		{PathRegex: EnvPathRegex("prod"), Age: KeyList{alice, bob}},
+		{PathRegex: EnvPathRegex("staging"), Age: KeyList{alice, bob}},
+		{PathRegex: DefaultPathRegex, Age: KeyList{alice}},
+	}}
+
+	recipients := func(cfg SOPSConfig, env string) KeyList {
+		return cfg.EnvRule(env).Age
+	}
+
+	// Adding a member leaves bob's direct grant alone
+	added := sopsCfg.Clone()
+	previous := groups.Grants(sopsCfg)
+	groups.Groups["devs"].Members = append(groups.Groups["devs"].Members, Member{Name: "carol", Key: carol})
+	groups.ApplyTo(&added, previous)
+	if got := recipients(added, "prod"); !reflect.DeepEqual(got, KeyList{alice, bob, carol}) {
+		t.Errorf("prod = %v after adding carol", got)
+	}
+	if got := recipients(added, "staging"); !reflect.DeepEqual(got, KeyList{alice, bob}) {
+		t.Errorf("staging = %v after adding carol, want bob's direct grant kept", got)
+	}
+
+	// Removing bob takes prod away but not staging
+	previous = groups.Grants(added)
+	groups.Groups["devs"].Members = []Member{{Name: "carol", Key: carol}}
+	groups.ApplyTo(&added, previous)
+	if got := recipients(added, "prod"); !reflect.DeepEqual(got, KeyList{alice, carol}) {
+		t.Errorf("prod = %v after removing bob", got)
+	}
+	if got := recipients(added, "staging"); !reflect.DeepEqual(got, KeyList{alice, bob}) {
+		t.Errorf("staging = %v after removing bob, want his direct grant kept", got)
+	}
+
+	// A group gaining an environment gets its own rule with the members
+	groups.Groups["devs"].Envs = append(groups.Groups["devs"].Envs, "qa")
+	previous = groups.Grants(added)
+	groups.ApplyTo(&added, previous)
+	if got := recipients(added, "qa"); !reflect.DeepEqual(got, KeyList{alice, carol}) {
+		t.Errorf("qa = %v, want the default recipients and the group", got)
+	}
+}
@@ -3,10 +3,10 @@ package project
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strings"
 
+	"github.com/jack-kitto/yoink/internal/git"
+	"github.com/jack-kitto/yoink/internal/github"
 	"github.com/jack-kitto/yoink/internal/util"
 	"gopkg.in/yaml.v3"
 )
@@ -14,18 +14,13 @@ import (
 type ProjectConfig struct {
 	VaultRepo   string `yaml:"vault"`
 	SecretsPath string `yaml:"secrets_file"`
+	Encryption  string `yaml:"encryption,omitempty"`
+	DefaultEnv  string `yaml:"default_env,omitempty"`
+	Forge       string `yaml:"forge,omitempty"`
+	ForgeURL    string `yaml:"forge_url,omitempty"`
 }
 
-type SOPSConfig struct {
-	CreationRules []CreationRule `yaml:"creation_rules"`
-}
-
-type CreationRule struct {
-	PathRegex string   `yaml:"path_regex"`
-	Age       []string `yaml:"age"`
-}
-
-func InitProject() error {
+func InitProject(encryption string) error {
 	// Check if we're in a git repository
 	repoName, err := util.GetGitRepoName()
 	if err != nil {
@@ -33,20 +28,22 @@ func InitProject() error {
 	}
 
 	// Get the GitHub username
-	username, err := getCurrentGitHubUser()
+	client := github.New("", "")
+	username, err := client.CurrentUser()
 	if err != nil {
 		return fmt.Errorf("failed to get GitHub username: %w", err)
 	}
 
 	// Create .yoink.yaml with proper vault URL including username
 	cfg := ProjectConfig{
-		VaultRepo:   fmt.Sprintf("git@github.com:%s/%s-vault.git", username, repoName),
+		VaultRepo:   fmt.Sprintf("git@%s:%s/%s-vault.git", client.Host, username, repoName),
 		SecretsPath: ".yoink/secrets.enc.yaml",
+		Encryption:  encryption,
 	}
 
 	// Check if .yoink.yaml already exists and has the same config
 	if existingCfg, err := LoadProject(); err == nil {
-		if existingCfg.VaultRepo == cfg.VaultRepo && existingCfg.SecretsPath == cfg.SecretsPath {
+		if existingCfg.VaultRepo == cfg.VaultRepo && existingCfg.SecretsPath == cfg.SecretsPath && existingCfg.Encryption == cfg.Encryption {
 			fmt.Println("ℹ️  Project configuration already exists and is correct")
 		} else {
 			// Update the configuration
@@ -89,26 +86,11 @@ func InitProject() error {
 	fmt.Println("✅ Project vault initialized")
 	fmt.Printf("📁 Vault repository: %s\n", cfg.VaultRepo)
 	fmt.Printf("🔐 Secrets file: %s\n", cfg.SecretsPath)
+	fmt.Printf("🔏 Encryption: %s\n", cfg.Encryption)
 
 	return nil
 }
 
-func getCurrentGitHubUser() (string, error) {
-	// Try to get username from gh CLI
-	cmd := exec.Command("gh", "api", "user", "--jq", ".login")
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("please authenticate with 'gh auth login' first")
-	}
-
-	username := strings.TrimSpace(string(output))
-	if username == "" {
-		return "", fmt.Errorf("could not determine GitHub username")
-	}
-
-	return username, nil
-}
-
 func LoadProject() (ProjectConfig, error) {
 	var c ProjectConfig
 
@@ -169,19 +151,20 @@ func IsVaultRepo(path string) bool {
 }
 
 func EnsureVaultRepo(cfg *ProjectConfig) error {
-	// Extract repo name from vault URL
-	vaultName := extractRepoName(cfg.VaultRepo)
-	if vaultName == "" {
-		return fmt.Errorf("invalid vault repository URL: %s", cfg.VaultRepo)
+	client, vaultName, err := git.GitHubClient(cfg.VaultRepo)
+	if err != nil {
+		return fmt.Errorf("%s is not a GitHub repository - create it yourself", cfg.VaultRepo)
 	}
 
 	// Check if repo exists
-	cmd := exec.Command("gh", "repo", "view", vaultName)
-	if err := cmd.Run(); err != nil {
+	exists, err := client.RepoExists(vaultName)
+	if err != nil {
+		return fmt.Errorf("failed to check vault repository: %w", err)
+	}
+	if !exists {
 		// Repo doesn't exist, create it
 		fmt.Printf("🔨 Creating vault repository: %s\n", vaultName)
-		createCmd := exec.Command("gh", "repo", "create", vaultName, "--private", "--description", "Yoink secrets vault")
-		if err := createCmd.Run(); err != nil {
+		if _, err := client.CreateRepo(vaultName, "Yoink secrets vault", true); err != nil {
 			return fmt.Errorf("failed to create vault repository: %w", err)
 		}
 	}
@@ -189,45 +172,6 @@ func EnsureVaultRepo(cfg *ProjectConfig) error {
 	return nil
 }
 
-func extractRepoName(gitURL string) string {
-	// Handle both SSH and HTTPS URLs
-	if strings.Contains(gitURL, "github.com:") {
-		// SSH format: git@github.com:user/repo.git
-		parts := strings.Split(gitURL, ":")
-		if len(parts) >= 2 {
-			repoPath := parts[1]
-			return strings.TrimSuffix(repoPath, ".git")
-		}
-	} else if strings.Contains(gitURL, "github.com/") {
-		// HTTPS format: https://github.com/user/repo.git
-		parts := strings.Split(gitURL, "github.com/")
-		if len(parts) >= 2 {
-			repoPath := parts[1]
-			return strings.TrimSuffix(repoPath, ".git")
-		}
-	}
-	return ""
-}
-
-func InitSOPSConfig(vaultPath string, publicKeys []string) error {
-	sopsConfig := SOPSConfig{
-		CreationRules: []CreationRule{
-			{
-				PathRegex: ".*\\.(yaml|yml|json)$",
-				Age:       publicKeys,
-			},
-		},
-	}
-
-	data, err := yaml.Marshal(sopsConfig)
-	if err != nil {
-		return err
-	}
-
-	sopsPath := filepath.Join(vaultPath, ".sops.yaml")
-	return os.WriteFile(sopsPath, data, 0644)
-}
-
 func GetVaultDir() (string, error) {
 	cfg, err := LoadProject()
 	if err != nil {
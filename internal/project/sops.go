@@ -0,0 +1,236 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvsDir is the vault directory holding one subdirectory per environment
+const EnvsDir = "envs"
+
+// DefaultPathRegex matches every secrets file not covered by an environment rule
+const DefaultPathRegex = `.*\.(yaml|yml|json)$`
+
+type SOPSConfig struct {
+	CreationRules []CreationRule `yaml:"creation_rules"`
+}
+
+type CreationRule struct {
+	PathRegex         string  `yaml:"path_regex"`
+	Age               KeyList `yaml:"age,omitempty"`
+	PGP               KeyList `yaml:"pgp,omitempty"`
+	EncryptedRegex    string  `yaml:"encrypted_regex,omitempty"`
+	UnencryptedSuffix string  `yaml:"unencrypted_suffix,omitempty"`
+}
+
+// KeyList holds recipient keys, which sops accepts either as a YAML list or
+// as a single comma-separated string
+type KeyList []string
+
+func (k *KeyList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*k = nil
+		for _, key := range strings.Split(value.Value, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				*k = append(*k, key)
+			}
+		}
+		return nil
+	}
+
+	var keys []string
+	if err := value.Decode(&keys); err != nil {
+		return err
+	}
+	*k = keys
+	return nil
+}
+
+// MarshalYAML writes keys in the comma-separated form every sops version understands
+func (k KeyList) MarshalYAML() (interface{}, error) {
+	return strings.Join(k, ","), nil
+}
+
+// Contains reports whether key is in the list
+func (k KeyList) Contains(key string) bool {
+	for _, existing := range k {
+		if existing == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Without returns a copy of the list with key removed
+func (k KeyList) Without(key string) KeyList {
+	out := KeyList{}
+	for _, existing := range k {
+		if existing != key {
+			out = append(out, existing)
+		}
+	}
+	return out
+}
+
+// KeysFor returns the rule's age list for age public keys and its PGP list
+// for anything else, which is treated as a PGP fingerprint
+func (r *CreationRule) KeysFor(key string) *KeyList {
+	if strings.HasPrefix(key, "age1") {
+		return &r.Age
+	}
+	return &r.PGP
+}
+
+// SameKeys reports whether two rules have the same recipients, ignoring order
+func (r CreationRule) SameKeys(other CreationRule) bool {
+	return sameKeys(r.Age, other.Age) && sameKeys(r.PGP, other.PGP)
+}
+
+func sameKeys(a, b KeyList) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, k := range a {
+		if !b.Contains(k) {
+			return false
+		}
+	}
+	return true
+}
+
+// EnvPathRegex returns the creation rule path_regex for an environment's files
+func EnvPathRegex(env string) string {
+	return "^" + EnvsDir + "/" + regexp.QuoteMeta(env) + "/.*"
+}
+
+// NewSOPSConfig builds a config with one rule per environment, ahead of a
+// default rule covering everything else
+func NewSOPSConfig(defaults CreationRule, envs map[string]CreationRule) SOPSConfig {
+	names := make([]string, 0, len(envs))
+	for name := range envs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var c SOPSConfig
+	for _, name := range names {
+		rule := envs[name]
+		rule.PathRegex = EnvPathRegex(name)
+		c.CreationRules = append(c.CreationRules, rule)
+	}
+
+	if defaults.PathRegex == "" {
+		defaults.PathRegex = DefaultPathRegex
+	}
+	c.CreationRules = append(c.CreationRules, defaults)
+	return c
+}
+
+// LoadSOPSConfig reads and parses a .sops.yaml file
+func LoadSOPSConfig(path string) (SOPSConfig, error) {
+	var c SOPSConfig
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return c, nil
+}
+
+// Clone returns a deep copy of the config
+func (c SOPSConfig) Clone() SOPSConfig {
+	out := SOPSConfig{CreationRules: make([]CreationRule, len(c.CreationRules))}
+	for i, r := range c.CreationRules {
+		out.CreationRules[i] = CreationRule{
+			PathRegex:         r.PathRegex,
+			Age:               append(KeyList{}, r.Age...),
+			PGP:               append(KeyList{}, r.PGP...),
+			EncryptedRegex:    r.EncryptedRegex,
+			UnencryptedSuffix: r.UnencryptedSuffix,
+		}
+	}
+	return out
+}
+
+// Save writes the config to path
+func (c SOPSConfig) Save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// EnvRule returns the creation rule for an environment. If there is none yet,
+// one is inserted ahead of the other rules, starting from the recipients and
+// encrypted_regex and unencrypted_suffix of the rule that currently covers
+// the environment.
+func (c *SOPSConfig) EnvRule(env string) *CreationRule {
+	pathRegex := EnvPathRegex(env)
+	for i := range c.CreationRules {
+		if c.CreationRules[i].PathRegex == pathRegex {
+			return &c.CreationRules[i]
+		}
+	}
+
+	rule := CreationRule{PathRegex: pathRegex}
+	if current := c.RuleFor(EnvsDir + "/" + env + "/secrets.enc.yaml"); current != nil {
+		rule.Age = append(KeyList{}, current.Age...)
+		rule.PGP = append(KeyList{}, current.PGP...)
+		rule.EncryptedRegex = current.EncryptedRegex
+		rule.UnencryptedSuffix = current.UnencryptedSuffix
+	}
+
+	c.CreationRules = append([]CreationRule{rule}, c.CreationRules...)
+	return &c.CreationRules[0]
+}
+
+// RemoveEnvRule drops an environment's creation rule, reporting whether it
+// had one
+func (c *SOPSConfig) RemoveEnvRule(env string) bool {
+	pathRegex := EnvPathRegex(env)
+	for i := range c.CreationRules {
+		if c.CreationRules[i].PathRegex == pathRegex {
+			c.CreationRules = append(c.CreationRules[:i], c.CreationRules[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// RuleFor returns the first rule matching a vault-relative path
+func (c *SOPSConfig) RuleFor(path string) *CreationRule {
+	for i, r := range c.CreationRules {
+		if r.PathRegex == "" {
+			return &c.CreationRules[i]
+		}
+		if re, err := regexp.Compile(r.PathRegex); err == nil && re.MatchString(path) {
+			return &c.CreationRules[i]
+		}
+	}
+	return nil
+}
+
+// ruleEnv returns the environment an environment rule belongs to, or "" for other rules
+func ruleEnv(pathRegex string) string {
+	prefix := "^" + EnvsDir + "/"
+	if !strings.HasPrefix(pathRegex, prefix) || !strings.HasSuffix(pathRegex, "/.*") {
+		return ""
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(pathRegex, prefix), "/.*")
+}
+
+func InitSOPSConfig(vaultPath string, defaults CreationRule, envs map[string]CreationRule) error {
+	return NewSOPSConfig(defaults, envs).Save(filepath.Join(vaultPath, ".sops.yaml"))
+}
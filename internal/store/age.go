@@ -0,0 +1,170 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+
+	"github.com/jack-kitto/yoink/internal/config"
+)
+
+// ageStanza is a copy of the data key encrypted to a single age recipient
+type ageStanza struct {
+	Recipient string `yaml:"recipient"`
+	Enc       string `yaml:"enc"`
+}
+
+// AgeEncryptor encrypts documents to the age recipients configured in
+// .sops.yaml and decrypts them with a local age key file
+type AgeEncryptor struct {
+	// KeyFile is the age identity file; yoink's own key is used when empty
+	KeyFile string
+}
+
+// NewAgeEncryptor creates an age encryptor using yoink's age key
+func NewAgeEncryptor() *AgeEncryptor {
+	return &AgeEncryptor{}
+}
+
+func (e *AgeEncryptor) Encrypt(plaintext []byte, path string) ([]byte, error) {
+	rule, err := creationRuleFor(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(rule.Age) == 0 {
+		return nil, fmt.Errorf("no age recipients configured for %s in .sops.yaml", path)
+	}
+
+	return encryptDocument(plaintext, func(dataKey []byte, meta *sopsMetadata) error {
+		stanzas, err := wrapAgeKey(dataKey, rule.Age)
+		if err != nil {
+			return err
+		}
+		meta.Age = stanzas
+		meta.EncryptedRegex, meta.UnencryptedSuffix = rule.EncryptedRegex, rule.UnencryptedSuffix
+		return nil
+	})
+}
+
+func (e *AgeEncryptor) Decrypt(ciphertext []byte) ([]byte, error) {
+	return decryptDocument(ciphertext, func(meta sopsMetadata) ([]byte, error) {
+		identities, err := e.identities()
+		if err != nil {
+			return nil, err
+		}
+		return unwrapAgeKey(meta.ageStanzas(), identities)
+	})
+}
+
+// identities reads the private keys from the encryptor's age key file
+func (e *AgeEncryptor) identities() ([]age.Identity, error) {
+	keyPath := e.KeyFile
+	if keyPath == "" {
+		var err error
+		if keyPath, err = config.GetAgeKeyPath(); err != nil {
+			return nil, fmt.Errorf("failed to get age key path: %w", err)
+		}
+	}
+
+	f, err := os.Open(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("age key not found at %s (run 'yoink init' to generate it): %w", keyPath, err)
+	}
+	defer f.Close()
+
+	identities, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse age key %s: %w", keyPath, err)
+	}
+	return identities, nil
+}
+
+// GenerateAgeKey writes a new X25519 identity to keyPath in age-keygen's
+// format and returns its public key
+func GenerateAgeKey(keyPath string) (string, error) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		return "", err
+	}
+
+	publicKey := identity.Recipient().String()
+	content := fmt.Sprintf("# created: %s\n# public key: %s\n%s\n",
+		time.Now().Format(time.RFC3339), publicKey, identity.String())
+
+	if err := os.WriteFile(keyPath, []byte(content), 0o600); err != nil {
+		return "", err
+	}
+	return publicKey, nil
+}
+
+// AgePublicKey returns the public key of the first X25519 identity in keyPath
+func AgePublicKey(keyPath string) (string, error) {
+	identities, err := (&AgeEncryptor{KeyFile: keyPath}).identities()
+	if err != nil {
+		return "", err
+	}
+	for _, id := range identities {
+		if x, ok := id.(*age.X25519Identity); ok {
+			return x.Recipient().String(), nil
+		}
+	}
+	return "", fmt.Errorf("no X25519 identity in %s", keyPath)
+}
+
+// wrapAgeKey encrypts the data key to each age recipient
+func wrapAgeKey(dataKey []byte, recipients []string) ([]ageStanza, error) {
+	stanzas := make([]ageStanza, 0, len(recipients))
+	for _, r := range recipients {
+		r = strings.TrimSpace(r)
+		recipient, err := age.ParseX25519Recipient(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid age recipient %q: %w", r, err)
+		}
+
+		var buf bytes.Buffer
+		aw := armor.NewWriter(&buf)
+		w, err := age.Encrypt(aw, recipient)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(dataKey); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		if err := aw.Close(); err != nil {
+			return nil, err
+		}
+
+		stanzas = append(stanzas, ageStanza{Recipient: r, Enc: buf.String()})
+	}
+	return stanzas, nil
+}
+
+// unwrapAgeKey recovers the data key from the first stanza one of the identities can open
+func unwrapAgeKey(stanzas []ageStanza, identities []age.Identity) ([]byte, error) {
+	if len(stanzas) == 0 {
+		return nil, fmt.Errorf("document has no age recipients")
+	}
+
+	for _, s := range stanzas {
+		r, err := age.Decrypt(armor.NewReader(strings.NewReader(s.Enc)), identities...)
+		if err != nil {
+			continue
+		}
+		dataKey, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return dataKey, nil
+	}
+
+	return nil, fmt.Errorf("none of the document's age recipients match your key - ask a vault maintainer to grant access")
+}
@@ -0,0 +1,138 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Change is a single operation in a batch applied to the secrets file
+type Change struct {
+	Key    string
+	Value  string
+	Delete bool
+}
+
+// String describes the change by key name only
+func (c Change) String() string {
+	if c.Delete {
+		return "unset " + c.Key
+	}
+	return "set " + c.Key
+}
+
+// ParseChanges parses a changes file of the form
+//
+//	set:
+//	  KEY: value
+//	unset:
+//	  - OTHER_KEY
+//
+// into changes ordered by key, sets first
+func ParseChanges(data []byte) ([]Change, error) {
+	var doc struct {
+		Set   yaml.Node `yaml:"set"`
+		Unset []string  `yaml:"unset"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	var sets map[string]string
+	if doc.Set.Kind != 0 {
+		var err error
+		if sets, err = decodeMapping(&doc.Set); err != nil {
+			return nil, fmt.Errorf("set: %w", err)
+		}
+	}
+
+	var changes []Change
+	for _, k := range sortedKeys(sets) {
+		changes = append(changes, Change{Key: k, Value: sets[k]})
+	}
+
+	unset := append([]string(nil), doc.Unset...)
+	sort.Strings(unset)
+	for _, k := range unset {
+		if err := ValidateKey(k); err != nil {
+			return nil, fmt.Errorf("unset: %w", err)
+		}
+		if _, exists := sets[k]; exists {
+			return nil, fmt.Errorf("%s is both set and unset", k)
+		}
+		changes = append(changes, Change{Key: k, Delete: true})
+	}
+
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("no changes: expected 'set' and/or 'unset' sections")
+	}
+	return changes, nil
+}
+
+// ParseAssignments parses KEY=VALUE arguments into changes
+func ParseAssignments(args []string) ([]Change, error) {
+	seen := make(map[string]bool)
+	changes := make([]Change, 0, len(args))
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid assignment %q: expected KEY=VALUE", arg)
+		}
+		if err := ValidateKey(key); err != nil {
+			return nil, err
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("%s is assigned more than once", key)
+		}
+		seen[key] = true
+		changes = append(changes, Change{Key: key, Value: value})
+	}
+	return changes, nil
+}
+
+// Apply loads the secrets once, applies every change and saves once; if any
+// change can't be applied nothing is written
+func (s *Store) Apply(changes []Change) error {
+	for _, c := range changes {
+		if err := ValidateKey(c.Key); err != nil {
+			return err
+		}
+	}
+	if s.dryRun {
+		fmt.Printf("🔍 [DRY RUN] Would apply %d changes to %s\n", len(changes), s.Path)
+		return nil
+	}
+
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	for _, c := range changes {
+		if c.Delete {
+			if _, exists := s.data[c.Key]; !exists {
+				return fmt.Errorf("secret '%s' not found", c.Key)
+			}
+		}
+	}
+
+	for _, c := range changes {
+		if c.Delete {
+			delete(s.data, c.Key)
+			delete(s.history, c.Key)
+		} else {
+			s.data[c.Key] = c.Value
+		}
+	}
+	return s.save()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
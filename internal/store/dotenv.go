@@ -0,0 +1,218 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// dotenvEntry is one KEY=value assignment, in file order
+type dotenvEntry struct {
+	Key   string
+	Value string
+}
+
+// ParseDotenv parses a .env or shell export file. It accepts an optional
+// "export" prefix, comments, unquoted values, single-quoted literals and
+// double-quoted values with escapes, and quoted values spanning lines.
+func ParseDotenv(data []byte) (map[string]string, error) {
+	entries, err := parseDotenvEntries(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string, len(entries))
+	for _, e := range entries {
+		result[e.Key] = e.Value
+	}
+	return result, nil
+}
+
+func parseDotenvEntries(src string) ([]dotenvEntry, error) {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	p := &dotenvParser{src: src, line: 1}
+
+	var entries []dotenvEntry
+	for {
+		p.skipBlankAndComments()
+		if p.eof() {
+			return entries, nil
+		}
+
+		line := p.line
+		key, err := p.key()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", line, key, err)
+		}
+		entries = append(entries, dotenvEntry{Key: key, Value: value})
+	}
+}
+
+type dotenvParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (p *dotenvParser) eof() bool { return p.pos >= len(p.src) }
+
+func (p *dotenvParser) peek() byte { return p.src[p.pos] }
+
+func (p *dotenvParser) next() byte {
+	c := p.src[p.pos]
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+func (p *dotenvParser) skipSpaces() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.next()
+	}
+}
+
+func (p *dotenvParser) skipLine() {
+	for !p.eof() && p.next() != '\n' {
+	}
+}
+
+func (p *dotenvParser) skipBlankAndComments() {
+	for !p.eof() {
+		p.skipSpaces()
+		if p.eof() {
+			return
+		}
+		switch p.peek() {
+		case '\n':
+			p.next()
+		case '#':
+			p.skipLine()
+		default:
+			return
+		}
+	}
+}
+
+// key reads "[export ]KEY=" and returns KEY
+func (p *dotenvParser) key() (string, error) {
+	if strings.HasPrefix(p.src[p.pos:], "export ") || strings.HasPrefix(p.src[p.pos:], "export\t") {
+		p.pos += len("export")
+		p.skipSpaces()
+	}
+
+	start := p.pos
+	for !p.eof() {
+		c := rune(p.peek())
+		if c != '_' && c != '.' && c != '-' && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			break
+		}
+		p.next()
+	}
+	key := p.src[start:p.pos]
+	if key == "" {
+		return "", fmt.Errorf("expected KEY=value")
+	}
+
+	p.skipSpaces()
+	if p.eof() || p.peek() != '=' {
+		return "", fmt.Errorf("expected '=' after %s", key)
+	}
+	p.next()
+	p.skipSpaces()
+	return key, nil
+}
+
+// value reads the rest of an assignment. Unquoted values run to the end of
+// the line or an inline " #" comment; quoted values follow shell rules and
+// may be concatenated, as in 'it'\''s'.
+func (p *dotenvParser) value() (string, error) {
+	if p.eof() || (p.peek() != '\'' && p.peek() != '"') {
+		start := p.pos
+		for !p.eof() && p.peek() != '\n' {
+			if p.peek() == '#' && p.pos > start && (p.src[p.pos-1] == ' ' || p.src[p.pos-1] == '\t') {
+				break
+			}
+			p.next()
+		}
+		value := strings.TrimSpace(p.src[start:p.pos])
+		p.skipLine()
+		return value, nil
+	}
+
+	var sb strings.Builder
+	for !p.eof() {
+		c := p.peek()
+		switch {
+		case c == '\'':
+			p.next()
+			closed := false
+			for !p.eof() {
+				ch := p.next()
+				if ch == '\'' {
+					closed = true
+					break
+				}
+				sb.WriteByte(ch)
+			}
+			if !closed {
+				return "", fmt.Errorf("unterminated single-quoted value")
+			}
+		case c == '"':
+			p.next()
+			if err := p.doubleQuoted(&sb); err != nil {
+				return "", err
+			}
+		case c == '\\' && p.pos+1 < len(p.src) && p.src[p.pos+1] != '\n':
+			p.next()
+			sb.WriteByte(p.next())
+		case c == ' ' || c == '\t' || c == '\n' || c == '#':
+			p.skipSpaces()
+			if !p.eof() && p.peek() == '#' {
+				p.skipLine()
+			} else if !p.eof() && p.peek() == '\n' {
+				p.next()
+			} else if !p.eof() {
+				return "", fmt.Errorf("unexpected text after quoted value")
+			}
+			return sb.String(), nil
+		default:
+			sb.WriteByte(p.next())
+		}
+	}
+	return sb.String(), nil
+}
+
+func (p *dotenvParser) doubleQuoted(sb *strings.Builder) error {
+	for !p.eof() {
+		c := p.next()
+		switch c {
+		case '"':
+			return nil
+		case '\\':
+			if p.eof() {
+				return fmt.Errorf("unterminated double-quoted value")
+			}
+			switch e := p.next(); e {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case '\n':
+				// line continuation
+			default:
+				sb.WriteByte(e)
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return fmt.Errorf("unterminated double-quoted value")
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// Supported encryption modes, as recorded in .yoink.yaml
+const (
+	EncryptionAge        = "age"
+	EncryptionPGP        = "pgp"
+	EncryptionPassphrase = "passphrase"
+)
+
+// Encryptor turns plaintext secrets documents into SOPS-style encrypted
+// documents and back
+type Encryptor interface {
+	// Encrypt encrypts a plaintext YAML document that will be written to path
+	Encrypt(plaintext []byte, path string) ([]byte, error)
+	// Decrypt decrypts an encrypted document and returns the plaintext YAML
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// NewEncryptor returns the Encryptor for an encryption mode, defaulting to age
+func NewEncryptor(mode string) (Encryptor, error) {
+	switch mode {
+	case "", EncryptionAge:
+		return NewAgeEncryptor(), nil
+	case EncryptionPGP, "gpg":
+		return NewPGPEncryptor(), nil
+	case EncryptionPassphrase:
+		return NewPassphraseEncryptor(), nil
+	default:
+		return nil, fmt.Errorf("unknown encryption mode %q (expected age, pgp or passphrase)", mode)
+	}
+}
+
+// pgpFingerprint matches a v4 or v6 OpenPGP fingerprint
+var pgpFingerprint = regexp.MustCompile(`^([0-9A-Fa-f]{40}|[0-9A-Fa-f]{64})$`)
+
+// ValidateRecipient checks that key is an age public key or a PGP
+// fingerprint, the two kinds of recipient .sops.yaml rules hold
+func ValidateRecipient(key string) error {
+	if strings.HasPrefix(key, "age1") {
+		if _, err := age.ParseX25519Recipient(key); err != nil {
+			return fmt.Errorf("invalid age public key %q: %w", key, err)
+		}
+		return nil
+	}
+	if !pgpFingerprint.MatchString(key) {
+		return fmt.Errorf("%q is neither an age public key nor a PGP fingerprint", key)
+	}
+	return nil
+}
+
+// encryptDocument generates a fresh data key, lets wrap record it in the
+// metadata and seals the plaintext with it
+func encryptDocument(plaintext []byte, wrap func(dataKey []byte, meta *sopsMetadata) error) ([]byte, error) {
+	dataKey, err := newDataKey()
+	if err != nil {
+		return nil, err
+	}
+
+	var meta sopsMetadata
+	if err := wrap(dataKey, &meta); err != nil {
+		return nil, err
+	}
+
+	return sealDocument(plaintext, dataKey, meta)
+}
+
+// decryptDocument opens an encrypted document and unseals it with the data
+// key recovered by unwrap
+func decryptDocument(ciphertext []byte, unwrap func(meta sopsMetadata) ([]byte, error)) ([]byte, error) {
+	doc, meta, err := openDocument(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := unwrap(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	return unsealDocument(doc, meta, dataKey)
+}
@@ -0,0 +1,33 @@
+package store
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+
+	"github.com/jack-kitto/yoink/internal/project"
+)
+
+// DefaultSecretsFile is the vault-relative secrets file used when no environment is selected
+const DefaultSecretsFile = "secrets.enc.yaml"
+
+// EnvsDir is the vault directory holding one subdirectory per environment
+const EnvsDir = project.EnvsDir
+
+var envNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// SecretsFile returns the vault-relative secrets file for an environment
+func SecretsFile(env string) string {
+	if env == "" {
+		return DefaultSecretsFile
+	}
+	return path.Join(EnvsDir, env, DefaultSecretsFile)
+}
+
+// ValidateEnvName checks that an environment name is safe to use as a directory
+func ValidateEnvName(env string) error {
+	if !envNameRe.MatchString(env) {
+		return fmt.Errorf("invalid environment name %q (use letters, digits, '-' and '_')", env)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Export formats, in addition to FormatDotenv and FormatJSON
+const (
+	FormatPOSIX      = "posix"
+	FormatFish       = "fish"
+	FormatPowerShell = "powershell"
+	FormatDocker     = "docker"
+	FormatTFVars     = "tfvars"
+)
+
+// ExportFormats lists the formats Serialize supports
+var ExportFormats = []string{FormatDotenv, FormatPOSIX, FormatFish, FormatPowerShell, FormatDocker, FormatTFVars, FormatJSON}
+
+var (
+	envNamePattern  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	hclNamePattern  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+	dotenvSafeValue = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,=-]*$`)
+)
+
+// Serialize writes secrets in the given format with keys in sorted order
+func Serialize(data map[string]string, format string) ([]byte, error) {
+	if format == FormatJSON {
+		out, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(out, '\n'), nil
+	}
+
+	names := envNamePattern
+	var line func(key, value string) (string, error)
+	switch format {
+	case FormatDotenv:
+		line = func(k, v string) (string, error) { return k + "=" + quoteDotenv(v), nil }
+	case FormatPOSIX:
+		line = func(k, v string) (string, error) { return "export " + k + "=" + quotePOSIX(v), nil }
+	case FormatFish:
+		line = func(k, v string) (string, error) { return "set -gx " + k + " " + quoteFish(v), nil }
+	case FormatPowerShell:
+		line = func(k, v string) (string, error) { return "$env:" + k + " = " + quotePowerShell(v), nil }
+	case FormatDocker:
+		line = func(k, v string) (string, error) {
+			if strings.ContainsAny(v, "\r\n") {
+				return "", fmt.Errorf("docker env files can't hold the multi-line value of %s", k)
+			}
+			return k + "=" + v, nil
+		}
+	case FormatTFVars:
+		names = hclNamePattern
+		line = func(k, v string) (string, error) { return k + " = " + quoteHCL(v), nil }
+	default:
+		return nil, fmt.Errorf("unknown format %q (expected %s)", format, strings.Join(ExportFormats, ", "))
+	}
+
+	var sb strings.Builder
+	for _, k := range sortedKeys(data) {
+		if !names.MatchString(k) {
+			return nil, fmt.Errorf("%s is not a valid variable name for %s output", k, format)
+		}
+		l, err := line(k, data[k])
+		if err != nil {
+			return nil, err
+		}
+		sb.WriteString(l)
+		sb.WriteByte('\n')
+	}
+	return []byte(sb.String()), nil
+}
+
+// quoteDotenv leaves simple values bare, single-quotes values without
+// quotes or newlines, and double-quotes the rest with escapes
+func quoteDotenv(v string) string {
+	if dotenvSafeValue.MatchString(v) {
+		return v
+	}
+	if !strings.ContainsAny(v, "'\r\n") {
+		return "'" + v + "'"
+	}
+
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "$", `\$`)
+	return `"` + r.Replace(v) + `"`
+}
+
+// quoteHCL writes an HCL string literal, escaping template sequences so
+// values are never interpolated
+func quoteHCL(v string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i, r := range v {
+		switch {
+		case r == '\\':
+			sb.WriteString(`\\`)
+		case r == '"':
+			sb.WriteString(`\"`)
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case r == '\t':
+			sb.WriteString(`\t`)
+		case (r == '$' || r == '%') && strings.HasPrefix(v[i+1:], "{"):
+			sb.WriteRune(r)
+			sb.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&sb, `\u%04X`, r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+func quotePOSIX(v string) string {
+	return "'" + strings.ReplaceAll(v, "'", `'\''`) + "'"
+}
+
+func quoteFish(v string) string {
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	return "'" + r.Replace(v) + "'"
+}
+
+func quotePowerShell(v string) string {
+	return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+}
@@ -1,72 +1,240 @@
 package store
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"strings"
+	"time"
 
-	"github.com/jack-kitto/yoink/internal/util"
-	"gopkg.in/yaml.v3"
+	"github.com/jack-kitto/yoink/internal/git"
+	"github.com/jack-kitto/yoink/internal/vault"
 )
 
 // FastStore provides quick access to secrets via HTTPS fetch
 type FastStore struct {
 	VaultRepo string
-	Branch    string
+	Ref       string // branch, tag or commit SHA; empty for the default branch
 	File      string
+	Cache     *vault.Cache // optional; keeps fetched files so reads work offline
+	enc       Encryptor
 	data      map[string]string
+	history   map[string][]HistoryEntry
+	found     bool
 }
 
 // NewFast creates a store that fetches via HTTPS when possible
-func NewFast(vaultRepo string) *FastStore {
+func NewFast(vaultRepo string, enc Encryptor) *FastStore {
 	return &FastStore{
 		VaultRepo: vaultRepo,
-		Branch:    "main",
 		File:      "secrets.enc.yaml",
+		enc:       enc,
 		data:      make(map[string]string),
 	}
 }
 
 func (s *FastStore) loadFast() error {
-	// Try fast HTTPS fetch first
-	content, err := util.FetchRawVaultFile(s.VaultRepo, s.Branch, s.File)
+	content, err := s.fetch()
+	if errors.Is(err, fs.ErrNotExist) {
+		s.data, s.history, s.found = make(map[string]string), nil, false
+		return nil
+	}
 	if err != nil {
-		return fmt.Errorf("fast fetch failed: %w", err)
+		return err
 	}
 
-	// Create temporary file for SOPS decryption
-	tmpFile, err := os.CreateTemp("", "yoink-fast-*.enc.yaml")
+	// Decrypt in memory - plaintext never touches disk
+	decrypted, err := s.enc.Decrypt(content)
 	if err != nil {
 		return err
 	}
-	defer os.Remove(tmpFile.Name())
-	defer tmpFile.Close()
 
-	// Write encrypted content to temp file
-	if _, err := tmpFile.WriteString(content); err != nil {
+	secrets, history, err := parseSecrets(decrypted)
+	if err != nil {
 		return err
 	}
-	tmpFile.Close()
+	s.data, s.history, s.found = secrets, history, true
+
+	return nil
+}
+
+// Found reports whether the last read found the secrets file in the vault,
+// which tells an empty environment from one that doesn't exist
+func (s *FastStore) Found() bool {
+	return s.found
+}
+
+// fetch returns the encrypted secrets file. With a cache it first asks the
+// vault which commit Ref is at and only downloads files the cache doesn't
+// hold for it; fs.ErrNotExist means the vault has no such file
+func (s *FastStore) fetch() ([]byte, error) {
+	if s.Cache == nil {
+		return s.download(s.Ref)
+	}
+	if vault.Offline {
+		return s.cached()
+	}
 
-	// Decrypt using existing SOPS logic
-	decrypted, err := DecryptToString(tmpFile.Name())
+	commit, err := s.Commit()
 	if err != nil {
-		return err
+		// Can't tell which commit is current; fetch the ref, falling
+		// back to the cache when the vault can't be reached at all
+		content, ferr := s.download(s.Ref)
+		if ferr == nil {
+			return content, nil
+		}
+		content, cerr := s.cached()
+		if cerr != nil {
+			return nil, ferr
+		}
+		return content, nil
+	}
+	if s.Ref == "" {
+		s.markLatest(commit)
+	}
+
+	content, err := s.Cache.ReadFile(commit, s.File)
+	if err == nil || errors.Is(err, fs.ErrNotExist) {
+		return content, err
 	}
 
-	// Parse YAML
-	var yamlData map[string]interface{}
-	if err := yaml.Unmarshal([]byte(decrypted), &yamlData); err != nil {
-		return fmt.Errorf("failed to parse decrypted YAML: %w", err)
+	if content, err = s.download(commit); err != nil {
+		return nil, err
 	}
+	if err := s.Cache.Put(commit, s.File, content); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not cache vault: %v\n", err)
+	}
+	return content, nil
+}
 
-	// Convert to string map
-	s.data = make(map[string]string)
-	for k, v := range yamlData {
-		s.data[k] = fmt.Sprintf("%v", v)
+// Commit returns the vault commit the store reads: Ref itself when it is a
+// full commit SHA, otherwise the commit Ref (or the default branch) points
+// at. With a token, GitHub vaults are asked with a conditional API request
+// that costs nothing when the ref hasn't moved; other vaults, and GitHub
+// without a token, with git ls-remote
+func (s *FastStore) Commit() (string, error) {
+	if isCommitSHA(s.Ref) {
+		return s.Ref, nil
 	}
 
-	return nil
+	if client, repo, err := git.GitHubClient(s.VaultRepo); err == nil && client.Token != "" {
+		name := s.Ref
+		if name == "" {
+			name = "HEAD"
+		}
+		var known vault.RefState
+		if s.Cache != nil {
+			known, _ = s.Cache.Ref(name)
+		}
+
+		sha, etag, notModified, err := client.CommitSHA(repo, s.Ref, known.ETag)
+		if err == nil && notModified {
+			return known.Commit, nil
+		}
+		if err == nil && sha != "" {
+			s.rememberRef(name, sha, etag)
+			return sha, nil
+		}
+	}
+
+	sha, err := vault.RemoteHead(s.VaultRepo, s.Ref)
+	if err == nil && s.Ref != "" {
+		s.rememberRef(s.Ref, sha, "")
+	}
+	return sha, err
+}
+
+// rememberRef records a resolved ref, so it can be read offline later
+func (s *FastStore) rememberRef(name, sha, etag string) {
+	if s.Cache == nil {
+		return
+	}
+	if err := s.Cache.SetRef(name, vault.RefState{Commit: sha, ETag: etag, UpdatedAt: time.Now().UTC()}); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not cache vault: %v\n", err)
+	}
+}
+
+// markLatest records the default branch's commit as the one offline reads use
+func (s *FastStore) markLatest(commit string) {
+	if latest, _, err := s.Cache.Latest(); err == nil && latest == commit {
+		return
+	}
+	if err := s.Cache.SetLatest(commit); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not cache vault: %v\n", err)
+	}
+}
+
+// download fetches the secrets file at a ref over HTTPS, which only GitHub
+// vaults support
+func (s *FastStore) download(ref string) ([]byte, error) {
+	client, repo, err := git.GitHubClient(s.VaultRepo)
+	if err != nil {
+		return nil, fmt.Errorf("fast fetch failed: %w", err)
+	}
+	content, err := client.File(repo, s.File, ref)
+	if err != nil {
+		return nil, fmt.Errorf("fast fetch failed: %w", err)
+	}
+	return content, nil
+}
+
+// cached reads the secrets file from the cache: at Ref when it is a commit
+// SHA, at the commit Ref was last seen at, or at the latest cached commit of
+// the default branch
+func (s *FastStore) cached() ([]byte, error) {
+	commit, updated, err := s.cachedCommit()
+	if err == nil {
+		var content []byte
+		content, err = s.Cache.ReadFile(commit, s.File)
+		if errors.Is(err, vault.ErrNotCached) && s.Ref == "" {
+			// The latest snapshot may only hold the files read since the
+			// branch last moved, such as another environment's
+			if snap, serr := s.Cache.LatestComplete(); serr == nil {
+				fmt.Fprintf(os.Stderr, "⚠️  %s isn't cached at %s, using the older copy from %s\n",
+					s.File, vault.ShortSHA(commit), vault.ShortSHA(snap.Commit))
+				commit, updated = snap.Commit, snap.Updated
+				content, err = s.Cache.ReadFile(commit, s.File)
+			}
+		}
+		if err == nil || errors.Is(err, fs.ErrNotExist) {
+			if !vault.Offline && !isCommitSHA(s.Ref) {
+				fmt.Fprintf(os.Stderr, "⚠️  Vault unreachable, using cached copy from %s (%s old)\n",
+					vault.ShortSHA(commit), time.Since(updated).Round(time.Minute))
+			}
+			return content, err
+		}
+	}
+	if errors.Is(err, vault.ErrNotCached) {
+		return nil, fmt.Errorf("%s is not cached - run 'yoink cache refresh' while online", s.File)
+	}
+	return nil, err
+}
+
+func (s *FastStore) cachedCommit() (string, time.Time, error) {
+	switch {
+	case isCommitSHA(s.Ref):
+		return s.Ref, time.Time{}, nil
+	case s.Ref != "":
+		state, err := s.Cache.Ref(s.Ref)
+		return state.Commit, state.UpdatedAt, err
+	default:
+		return s.Cache.Latest()
+	}
+}
+
+// isCommitSHA reports whether ref is a full SHA-1 or SHA-256 commit id,
+// which names the same content forever
+func isCommitSHA(ref string) bool {
+	if len(ref) != 40 && len(ref) != 64 {
+		return false
+	}
+	for _, c := range ref {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
 }
 
 func (s *FastStore) Get(key string) (string, error) {
@@ -81,6 +249,15 @@ func (s *FastStore) Get(key string) (string, error) {
 	return value, nil
 }
 
+// Previous returns a rotated secret's previous value while it is within its
+// grace period
+func (s *FastStore) Previous(key string) (string, error) {
+	if err := s.loadFast(); err != nil {
+		return "", err
+	}
+	return previousValue(s.history, key)
+}
+
 func (s *FastStore) All() (map[string]string, error) {
 	if err := s.loadFast(); err != nil {
 		return nil, err
@@ -112,9 +289,9 @@ func (s *FastStore) ExportEnv() (string, error) {
 		return "", err
 	}
 
-	var sb strings.Builder
-	for k, v := range data {
-		sb.WriteString(fmt.Sprintf("%s=%s\n", k, v))
+	out, err := Serialize(data, FormatDotenv)
+	if err != nil {
+		return "", err
 	}
-	return sb.String(), nil
+	return string(out), nil
 }
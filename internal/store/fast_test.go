@@ -0,0 +1,79 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jack-kitto/yoink/internal/vault"
+)
+
+func TestOfflineReadFallsBackToCompleteSnapshot(t *testing.T) {
+	const older = "1111111111111111111111111111111111111111"
+	const newer = "2222222222222222222222222222222222222222"
+	const prodFile = "envs/prod/secrets.enc.yaml"
+
+	cache := &vault.Cache{Dir: t.TempDir()}
+
+	// A clone cached the whole vault at the older commit
+	repo := t.TempDir()
+	for file, content := range map[string]string{
+		"secrets.enc.yaml": "ENV: dev\n",
+		prodFile:           "ENV: prod-old\n",
+	} {
+		path := filepath.Join(repo, filepath.FromSlash(file))
+		os.MkdirAll(filepath.Dir(path), 0o700)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cache.Store(older, repo); err != nil {
+		t.Fatal(err)
+	}
+
+	// A fast read of the default environment then cached only its file at
+	// the newer commit
+	if err := cache.Put(newer, "secrets.enc.yaml", []byte("ENV: dev-new\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.SetLatest(newer); err != nil {
+		t.Fatal(err)
+	}
+
+	vault.Offline = true
+	t.Cleanup(func() { vault.Offline = false })
+
+	read := func(file string) string {
+		s := NewFast("https://github.com/o/vault.git", plainEncryptor{})
+		s.File, s.Cache = file, cache
+		v, err := s.Get("ENV")
+		if err != nil {
+			t.Fatalf("Get(ENV) from %s: %v", file, err)
+		}
+		return v
+	}
+
+	if got := read("secrets.enc.yaml"); got != "dev-new" {
+		t.Errorf("default environment = %q, want the latest copy", got)
+	}
+	if got := read(prodFile); got != "prod-old" {
+		t.Errorf("prod environment = %q, want the complete snapshot's copy", got)
+	}
+
+	s := NewFast("https://github.com/o/vault.git", plainEncryptor{})
+	s.File, s.Cache = "envs/staging/secrets.enc.yaml", cache
+	if keys, err := s.Keys(); err != nil || len(keys) != 0 {
+		t.Errorf("missing environment = %v, %v; want no secrets", keys, err)
+	}
+
+	// Without a complete snapshot the file is reported as not cached
+	partial := &vault.Cache{Dir: t.TempDir()}
+	partial.Put(newer, "secrets.enc.yaml", []byte("ENV: dev-new\n"))
+	partial.SetLatest(newer)
+	s = NewFast("https://github.com/o/vault.git", plainEncryptor{})
+	s.File, s.Cache = prodFile, partial
+	if _, err := s.Get("ENV"); err == nil || !strings.Contains(err.Error(), "not cached") {
+		t.Errorf("err = %v, want not cached", err)
+	}
+}
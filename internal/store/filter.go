@@ -0,0 +1,115 @@
+package store
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Filter selects and renames secrets before they are handed to a service.
+// Prefix, Include and Exclude match vault key names; secrets named in Map
+// are always passed on under their new name
+type Filter struct {
+	// Prefix keeps only keys starting with it
+	Prefix string
+	// StripPrefix removes Prefix from the keys that are kept
+	StripPrefix bool
+	// Include keeps only keys matching one of these glob patterns
+	Include []string
+	// Exclude drops keys matching any of these glob patterns
+	Exclude []string
+	// Map passes the secret SRC on as DEST
+	Map map[string]string
+}
+
+// IsZero reports whether the filter passes all secrets through unchanged
+func (f Filter) IsZero() bool {
+	return f.Prefix == "" && !f.StripPrefix && len(f.Include) == 0 && len(f.Exclude) == 0 && len(f.Map) == 0
+}
+
+// Validate checks the filter's options and glob patterns
+func (f Filter) Validate() error {
+	if f.StripPrefix && f.Prefix == "" {
+		return fmt.Errorf("--strip-prefix needs a --prefix to strip")
+	}
+	for _, patterns := range [][]string{f.Include, f.Exclude} {
+		for _, p := range patterns {
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", p, err)
+			}
+		}
+	}
+	for src, dest := range f.Map {
+		if src == "" || dest == "" {
+			return fmt.Errorf("invalid mapping %s=%s (expected SRC=DEST)", src, dest)
+		}
+	}
+	return nil
+}
+
+// Apply returns the secrets that pass the filter under their final names
+func (f Filter) Apply(data map[string]string) (map[string]string, error) {
+	if f.IsZero() {
+		return data, nil
+	}
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]string)
+	from := make(map[string]string)
+	add := func(src, name string) error {
+		if prev, ok := from[name]; ok {
+			return fmt.Errorf("%s and %s would both be passed on as %s", prev, src, name)
+		}
+		from[name] = src
+		out[name] = data[src]
+		return nil
+	}
+
+	for _, k := range sortedKeys(data) {
+		if _, mapped := f.Map[k]; mapped || !f.keep(k) {
+			continue
+		}
+		name := k
+		if f.StripPrefix {
+			name = strings.TrimPrefix(k, f.Prefix)
+			if name == "" {
+				continue
+			}
+		}
+		if err := add(k, name); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, src := range sortedKeys(f.Map) {
+		if _, ok := data[src]; !ok {
+			return nil, fmt.Errorf("secret %s not found", src)
+		}
+		if err := add(src, f.Map[src]); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// keep reports whether a vault key passes the prefix and pattern filters
+func (f Filter) keep(key string) bool {
+	if !strings.HasPrefix(key, f.Prefix) {
+		return false
+	}
+	if len(f.Include) > 0 && !matchAny(f.Include, key) {
+		return false
+	}
+	return !matchAny(f.Exclude, key)
+}
+
+func matchAny(patterns []string, key string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, key); ok {
+			return true
+		}
+	}
+	return false
+}
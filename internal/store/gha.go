@@ -0,0 +1,62 @@
+package store
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// FormatGHA writes secrets to a GitHub Actions $GITHUB_ENV file
+const FormatGHA = "gha"
+
+// GitHubEnv returns secrets as KEY<<DELIMITER blocks for $GITHUB_ENV, in
+// sorted order, along with the ::add-mask:: workflow commands that hide
+// their values from the job log
+func GitHubEnv(data map[string]string) (env, masks []byte, err error) {
+	var envOut, maskOut strings.Builder
+	for _, k := range sortedKeys(data) {
+		if !envNamePattern.MatchString(k) {
+			return nil, nil, fmt.Errorf("%s is not a valid variable name for %s output", k, FormatGHA)
+		}
+		v := data[k]
+
+		delim, err := heredocDelimiter(v)
+		if err != nil {
+			return nil, nil, err
+		}
+		fmt.Fprintf(&envOut, "%s<<%s\n%s\n%s\n", k, delim, v, delim)
+
+		// The runner masks line by line, so multi-line values need a
+		// mask per line
+		for _, line := range strings.Split(strings.ReplaceAll(v, "\r\n", "\n"), "\n") {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			fmt.Fprintf(&maskOut, "::add-mask::%s\n", escapeWorkflowData(line))
+		}
+	}
+	return []byte(envOut.String()), []byte(maskOut.String()), nil
+}
+
+// heredocDelimiter picks a random delimiter that doesn't occur in the value,
+// so a value can't end its block early and inject other variables
+func heredocDelimiter(value string) (string, error) {
+	for {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		delim := "ghadelimiter_" + hex.EncodeToString(b)
+		if !strings.Contains(value, delim) {
+			return delim, nil
+		}
+	}
+}
+
+// escapeWorkflowData escapes a workflow command's data the way the Actions
+// toolkit does
+func escapeWorkflowData(s string) string {
+	r := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	return r.Replace(s)
+}
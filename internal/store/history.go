@@ -0,0 +1,163 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// HistoryKey is the reserved top-level key holding previous values of
+// rotated secrets
+const HistoryKey = "yoink_history"
+
+// ValidateKey rejects key names yoink reserves in the secrets document: the
+// rotation history and the SOPS metadata
+func ValidateKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("secret name can't be empty")
+	}
+	if key == HistoryKey || key == "sops" {
+		return fmt.Errorf("%s is a reserved key", key)
+	}
+	return nil
+}
+
+// HistoryEntry is a previous value of a rotated secret, kept until it expires
+type HistoryEntry struct {
+	Value     string `yaml:"value"`
+	RotatedAt string `yaml:"rotated_at"`
+	ExpiresAt string `yaml:"expires_at"`
+}
+
+// Expired reports whether the entry's grace period has passed
+func (e HistoryEntry) Expired(now time.Time) bool {
+	expires, err := time.Parse(time.RFC3339, e.ExpiresAt)
+	return err != nil || !now.Before(expires)
+}
+
+// parseSecrets splits a decrypted secrets document into its secrets and the
+// history of rotated values. Scalar values are returned exactly as written,
+// so numbers, booleans and multi-line values keep their original text.
+func parseSecrets(plaintext []byte) (map[string]string, map[string][]HistoryEntry, error) {
+	data := make(map[string]string)
+	history := make(map[string][]HistoryEntry)
+	if len(plaintext) == 0 {
+		return data, history, nil
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(plaintext, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse decrypted YAML: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return data, history, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("failed to parse decrypted YAML: secrets document is not a mapping")
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i].Value, root.Content[i+1]
+
+		if key == HistoryKey {
+			var entries map[string][]HistoryEntry
+			if err := value.Decode(&entries); err != nil {
+				return nil, nil, fmt.Errorf("failed to parse %s: %w", HistoryKey, err)
+			}
+			for k, e := range entries {
+				history[k] = e
+			}
+			continue
+		}
+
+		text, err := nodeText(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse %s: %w", key, err)
+		}
+		data[key] = text
+	}
+
+	return data, history, nil
+}
+
+// nodeText returns a scalar's literal text, or nested YAML as text
+func nodeText(n *yaml.Node) (string, error) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Kind == yaml.ScalarNode {
+		if n.ShortTag() == "!!null" {
+			return "", nil
+		}
+		return n.Value, nil
+	}
+
+	out, err := yaml.Marshal(n)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// marshalSecrets builds the plaintext secrets document, dropping history
+// entries whose grace period has passed
+func marshalSecrets(data map[string]string, history map[string][]HistoryEntry) ([]byte, error) {
+	pruneHistory(history, time.Now())
+	if len(history) == 0 {
+		return yaml.Marshal(data)
+	}
+
+	doc := make(map[string]interface{}, len(data)+1)
+	for k, v := range data {
+		doc[k] = v
+	}
+	doc[HistoryKey] = history
+	return yaml.Marshal(doc)
+}
+
+func pruneHistory(history map[string][]HistoryEntry, now time.Time) {
+	for k, entries := range history {
+		kept := entries[:0]
+		for _, e := range entries {
+			if !e.Expired(now) {
+				kept = append(kept, e)
+			}
+		}
+		if len(kept) == 0 {
+			delete(history, k)
+		} else {
+			history[k] = kept
+		}
+	}
+}
+
+// previousValue returns the most recently rotated-out value of key that is
+// still within its grace period
+func previousValue(history map[string][]HistoryEntry, key string) (string, error) {
+	now := time.Now()
+	entries := history[key]
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !entries[i].Expired(now) {
+			return entries[i].Value, nil
+		}
+	}
+	return "", fmt.Errorf("secret '%s' has no previous value within its grace period", key)
+}
+
+// DecryptSecrets decrypts an encrypted secrets file held in memory, such as
+// an old revision read from the vault's history
+func DecryptSecrets(ciphertext []byte, enc Encryptor) (map[string]string, error) {
+	if len(ciphertext) == 0 {
+		return map[string]string{}, nil
+	}
+	plaintext, err := enc.Decrypt(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	secrets, _, err := parseSecrets(plaintext)
+	return secrets, err
+}
@@ -0,0 +1,188 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Import formats
+const (
+	FormatDotenv = "dotenv"
+	FormatJSON   = "json"
+	FormatYAML   = "yaml"
+	FormatSOPS   = "sops"
+)
+
+// DetectFormat guesses an import file's format from its contents and name
+func DetectFormat(path string, data []byte) string {
+	if bytes.Contains(data, []byte("ENC[AES256_GCM,")) {
+		return FormatSOPS
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	}
+	return FormatDotenv
+}
+
+// ParseImport parses secrets from data in the given format. SOPS files
+// (YAML, JSON or dotenv) are decrypted in memory with enc.
+func ParseImport(data []byte, format string, enc Encryptor) (map[string]string, error) {
+	switch format {
+	case FormatDotenv:
+		return ParseDotenv(data)
+	case FormatJSON:
+		return parseJSON(data)
+	case FormatYAML:
+		return DecodeYAML(data)
+	case FormatSOPS:
+		if isSOPSDotenv(data) {
+			converted, err := sopsDotenvToYAML(data)
+			if err != nil {
+				return nil, err
+			}
+			data = converted
+		}
+		plaintext, err := enc.Decrypt(data)
+		if err != nil {
+			return nil, err
+		}
+		secrets, _, err := parseSecrets(plaintext)
+		return secrets, err
+	default:
+		return nil, fmt.Errorf("unknown format %q (expected dotenv, json, yaml or sops)", format)
+	}
+}
+
+// parseJSON parses a flat JSON object; numbers and booleans keep their text
+func parseJSON(data []byte) (map[string]string, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string, len(raw))
+	for k, v := range raw {
+		var s string
+		if err := json.Unmarshal(v, &s); err == nil {
+			result[k] = s
+			continue
+		}
+
+		text := string(bytes.TrimSpace(v))
+		if strings.HasPrefix(text, "{") || strings.HasPrefix(text, "[") {
+			return nil, fmt.Errorf("value of %s must be a string, not a nested structure", k)
+		}
+		if text == "null" {
+			text = ""
+		}
+		result[k] = text
+	}
+	return result, nil
+}
+
+// isSOPSDotenv reports whether data is a sops-encrypted dotenv file
+func isSOPSDotenv(data []byte) bool {
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "sops_mac=") || strings.HasPrefix(line, "sops_version=") {
+			return true
+		}
+	}
+	return false
+}
+
+// sopsDotenvToYAML converts a sops-encrypted dotenv file into the equivalent
+// encrypted YAML document. sops flattens its metadata into sops_ keys, with
+// nesting written as __map_<key> and __list_<index>.
+func sopsDotenvToYAML(data []byte) ([]byte, error) {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	meta := map[string]interface{}{}
+
+	for i, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=value", i+1)
+		}
+		value = strings.ReplaceAll(value, "\\n", "\n")
+
+		if rest, isMeta := strings.CutPrefix(key, "sops_"); isMeta {
+			setFlattened(meta, strings.Split(rest, "__"), value)
+			continue
+		}
+		root.Content = append(root.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+	}
+
+	metaNode := &yaml.Node{}
+	if err := metaNode.Encode(unflattenLists(meta)); err != nil {
+		return nil, err
+	}
+	root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "sops"}, metaNode)
+
+	return yaml.Marshal(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}})
+}
+
+func setFlattened(tree map[string]interface{}, path []string, value string) {
+	for i, segment := range path {
+		segment = strings.TrimPrefix(segment, "map_")
+		if i == len(path)-1 {
+			tree[segment] = value
+			return
+		}
+		child, ok := tree[segment].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			tree[segment] = child
+		}
+		tree = child
+	}
+}
+
+// unflattenLists turns maps keyed list_0, list_1... back into lists
+func unflattenLists(v interface{}) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+
+	isList := len(m) > 0
+	for k, child := range m {
+		m[k] = unflattenLists(child)
+		if !strings.HasPrefix(k, "list_") {
+			isList = false
+		}
+	}
+	if !isList {
+		return m
+	}
+
+	indexes := make([]int, 0, len(m))
+	for k := range m {
+		n, err := strconv.Atoi(strings.TrimPrefix(k, "list_"))
+		if err != nil {
+			return m
+		}
+		indexes = append(indexes, n)
+	}
+	sort.Ints(indexes)
+
+	list := make([]interface{}, 0, len(m))
+	for _, n := range indexes {
+		list = append(list, m["list_"+strconv.Itoa(n)])
+	}
+	return list
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Kubernetes manifest formats
+const (
+	FormatK8sSecret    = "k8s-secret"
+	FormatK8sConfigMap = "k8s-configmap"
+)
+
+// ManifestOptions describes the metadata of a generated Kubernetes manifest
+type ManifestOptions struct {
+	Name        string
+	Namespace   string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+type k8sManifest struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   k8sMetadata       `yaml:"metadata"`
+	Type       string            `yaml:"type,omitempty"`
+	Data       map[string]string `yaml:"data"`
+}
+
+type k8sMetadata struct {
+	Name        string            `yaml:"name"`
+	Namespace   string            `yaml:"namespace,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+var k8sKeyPattern = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+
+// KubernetesManifest builds a v1 Secret (base64 data) or ConfigMap manifest
+// holding the secrets
+func KubernetesManifest(data map[string]string, format string, opts ManifestOptions) ([]byte, error) {
+	if opts.Name == "" {
+		return nil, fmt.Errorf("a manifest name is required")
+	}
+
+	m := k8sManifest{
+		APIVersion: "v1",
+		Metadata: k8sMetadata{
+			Name:        opts.Name,
+			Namespace:   opts.Namespace,
+			Labels:      opts.Labels,
+			Annotations: opts.Annotations,
+		},
+		Data: make(map[string]string, len(data)),
+	}
+
+	switch format {
+	case FormatK8sSecret:
+		m.Kind, m.Type = "Secret", "Opaque"
+	case FormatK8sConfigMap:
+		m.Kind = "ConfigMap"
+	default:
+		return nil, fmt.Errorf("unknown manifest format %q", format)
+	}
+
+	for k, v := range data {
+		if !k8sKeyPattern.MatchString(k) {
+			return nil, fmt.Errorf("%s is not a valid %s key", k, m.Kind)
+		}
+		if format == FormatK8sSecret {
+			v = base64.StdEncoding.EncodeToString([]byte(v))
+		}
+		m.Data[k] = v
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(m); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,149 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+
+	"github.com/jack-kitto/yoink/internal/util"
+)
+
+// PassphraseEnv is the environment variable consulted for the vault passphrase
+// before prompting
+const PassphraseEnv = "YOINK_PASSPHRASE"
+
+// PassphraseEncryptor wraps the data key with a scrypt-derived key from a
+// shared passphrase instead of per-user keys. Documents it writes can only be
+// opened by yoink, not by the sops CLI. A .sops.yaml is optional; when there
+// is one, its creation rule decides which values are encrypted.
+type PassphraseEncryptor struct {
+	// Passphrase returns the passphrase to use
+	Passphrase func() (string, error)
+	// Confirm asks for the passphrase again before it creates a new file, so
+	// a mistyped passphrase can't lock the file away; nil skips the check
+	Confirm func() (string, error)
+	cached  string
+	// verified is set once the passphrase has decrypted a document or been
+	// confirmed
+	verified bool
+}
+
+// NewPassphraseEncryptor creates a passphrase encryptor that reads
+// YOINK_PASSPHRASE or prompts on the terminal
+func NewPassphraseEncryptor() *PassphraseEncryptor {
+	return &PassphraseEncryptor{Passphrase: defaultPassphrase, Confirm: confirmPassphrase}
+}
+
+func defaultPassphrase() (string, error) {
+	if p := os.Getenv(PassphraseEnv); p != "" {
+		return p, nil
+	}
+	return util.PromptHidden("🔑 Vault passphrase: ")
+}
+
+func confirmPassphrase() (string, error) {
+	if p := os.Getenv(PassphraseEnv); p != "" {
+		return p, nil
+	}
+	return util.PromptHidden("🔑 Confirm vault passphrase: ")
+}
+
+func (e *PassphraseEncryptor) Encrypt(plaintext []byte, path string) ([]byte, error) {
+	rule, err := creationRuleFor(path)
+	if err != nil && !errors.Is(err, errNoSOPSConfig) {
+		return nil, err
+	}
+
+	pass, err := e.passphrase()
+	if err != nil {
+		return nil, err
+	}
+	if !e.verified && e.Confirm != nil && !util.FileExists(path) {
+		again, err := e.Confirm()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read passphrase: %w", err)
+		}
+		if again != pass {
+			e.cached = ""
+			return nil, fmt.Errorf("passphrases don't match")
+		}
+		e.verified = true
+	}
+
+	return encryptDocument(plaintext, func(dataKey []byte, meta *sopsMetadata) error {
+		meta.EncryptedRegex, meta.UnencryptedSuffix = rule.EncryptedRegex, rule.UnencryptedSuffix
+
+		recipient, err := age.NewScryptRecipient(pass)
+		if err != nil {
+			return err
+		}
+
+		var buf bytes.Buffer
+		aw := armor.NewWriter(&buf)
+		w, err := age.Encrypt(aw, recipient)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(dataKey); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		if err := aw.Close(); err != nil {
+			return err
+		}
+
+		meta.Passphrase = buf.String()
+		return nil
+	})
+}
+
+func (e *PassphraseEncryptor) Decrypt(ciphertext []byte) ([]byte, error) {
+	return decryptDocument(ciphertext, func(meta sopsMetadata) ([]byte, error) {
+		if meta.Passphrase == "" {
+			return nil, fmt.Errorf("document is not passphrase encrypted")
+		}
+
+		pass, err := e.passphrase()
+		if err != nil {
+			return nil, err
+		}
+
+		identity, err := age.NewScryptIdentity(pass)
+		if err != nil {
+			return nil, err
+		}
+
+		r, err := age.Decrypt(armor.NewReader(strings.NewReader(meta.Passphrase)), identity)
+		if err != nil {
+			return nil, fmt.Errorf("wrong passphrase: %w", err)
+		}
+		e.verified = true
+		return io.ReadAll(r)
+	})
+}
+
+// passphrase returns the passphrase, asking for it at most once
+func (e *PassphraseEncryptor) passphrase() (string, error) {
+	if e.cached != "" {
+		return e.cached, nil
+	}
+
+	pass, err := e.Passphrase()
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	if pass == "" {
+		return "", fmt.Errorf("passphrase must not be empty")
+	}
+
+	e.cached = pass
+	return pass, nil
+}
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// pgpStanza is a copy of the data key encrypted to a single OpenPGP key
+type pgpStanza struct {
+	CreatedAt   string `yaml:"created_at"`
+	Enc         string `yaml:"enc"`
+	Fingerprint string `yaml:"fp"`
+}
+
+// PGPEncryptor encrypts documents to the OpenPGP fingerprints configured in
+// .sops.yaml, delegating key operations to the local gpg installation
+type PGPEncryptor struct {
+	// GPGBinary is the gpg executable to run
+	GPGBinary string
+}
+
+// NewPGPEncryptor creates an OpenPGP encryptor backed by gpg
+func NewPGPEncryptor() *PGPEncryptor {
+	return &PGPEncryptor{GPGBinary: "gpg"}
+}
+
+func (e *PGPEncryptor) Encrypt(plaintext []byte, path string) ([]byte, error) {
+	rule, err := creationRuleFor(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(rule.PGP) == 0 {
+		return nil, fmt.Errorf("no pgp fingerprints configured for %s in .sops.yaml", path)
+	}
+
+	return encryptDocument(plaintext, func(dataKey []byte, meta *sopsMetadata) error {
+		meta.EncryptedRegex, meta.UnencryptedSuffix = rule.EncryptedRegex, rule.UnencryptedSuffix
+		for _, fp := range rule.PGP {
+			fp = strings.TrimSpace(fp)
+			enc, err := e.gpg(dataKey, "--no-default-recipient", "--yes", "--trust-model", "always",
+				"--encrypt", "--armor", "--recipient", fp, "--no-encrypt-to")
+			if err != nil {
+				return fmt.Errorf("gpg encryption to %s failed: %w", fp, err)
+			}
+			meta.PGP = append(meta.PGP, pgpStanza{
+				CreatedAt:   time.Now().UTC().Format(time.RFC3339),
+				Enc:         string(enc),
+				Fingerprint: fp,
+			})
+		}
+		return nil
+	})
+}
+
+func (e *PGPEncryptor) Decrypt(ciphertext []byte) ([]byte, error) {
+	return decryptDocument(ciphertext, func(meta sopsMetadata) ([]byte, error) {
+		stanzas := meta.pgpStanzas()
+		if len(stanzas) == 0 {
+			return nil, fmt.Errorf("document has no pgp recipients")
+		}
+
+		for _, s := range stanzas {
+			dataKey, err := e.gpg([]byte(s.Enc), "--use-agent", "--decrypt")
+			if err == nil {
+				return dataKey, nil
+			}
+		}
+		return nil, fmt.Errorf("none of the document's pgp keys are available in your gpg keyring")
+	})
+}
+
+// gpg runs the gpg binary with input on stdin and returns its stdout
+func (e *PGPEncryptor) gpg(input []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command(e.GPGBinary, append([]string{"--quiet", "--batch"}, args...)...)
+	cmd.Stdin = bytes.NewReader(input)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
@@ -1,148 +1,92 @@
 package store
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strings"
+	"regexp"
 
-	"github.com/jack-kitto/yoink/internal/config"
+	"github.com/jack-kitto/yoink/internal/project"
 )
 
-// setAgeKeyEnv sets the SOPS_AGE_KEY_FILE environment variable to point to yoink's age key
-func setAgeKeyEnv() error {
-	keyPath, err := config.GetAgeKeyPath()
-	if err != nil {
-		return fmt.Errorf("failed to get age key path: %w", err)
-	}
-
-	if _, err := os.Stat(keyPath); err != nil {
-		return fmt.Errorf("age key not found at %s (run 'yoink init' to generate it): %w", keyPath, err)
-	}
+// errNoSOPSConfig means no .sops.yaml was found above a path
+var errNoSOPSConfig = errors.New(".sops.yaml configuration not found - run 'yoink vault-init' to set up encryption")
 
-	os.Setenv("SOPS_AGE_KEY_FILE", keyPath)
-	return nil
+// CheckSOPSConfig verifies that SOPS configuration is available
+func CheckSOPSConfig(dir string) error {
+	_, err := findSOPSConfig(dir)
+	return err
 }
 
-// EncryptWithSOPS uses the sops CLI to encrypt a YAML or JSON file
-func EncryptWithSOPS(input, output string) error {
-	if err := setAgeKeyEnv(); err != nil {
-		return err
-	}
-
-	cmd := exec.Command("sops", "-e", input)
-	data, err := cmd.Output()
+// findSOPSConfig returns the path of the nearest .sops.yaml, starting from
+// the given directory and walking up
+func findSOPSConfig(dir string) (string, error) {
+	currentDir, err := filepath.Abs(dir)
 	if err != nil {
-		// Try to get stderr for better error message
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return fmt.Errorf("sops encryption failed: %s", string(exitErr.Stderr))
-		}
-		return fmt.Errorf("sops encryption failed: %w", err)
+		return "", err
 	}
-	return os.WriteFile(output, data, 0o600)
-}
 
-// DecryptWithSOPS uses the sops CLI to decrypt a YAML or JSON file
-func DecryptWithSOPS(input, output string) error {
-	if err := setAgeKeyEnv(); err != nil {
-		return err
-	}
+	for {
+		sopsPath := filepath.Join(currentDir, ".sops.yaml")
+		if _, err := os.Stat(sopsPath); err == nil {
+			return sopsPath, nil
+		}
 
-	cmd := exec.Command("sops", "-d", input)
-	data, err := cmd.Output()
-	if err != nil {
-		// Try to get stderr for better error message
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return fmt.Errorf("sops decryption failed: %s", string(exitErr.Stderr))
+		parent := filepath.Dir(currentDir)
+		if parent == currentDir {
+			// Reached root
+			break
 		}
-		return fmt.Errorf("sops decryption failed: %w", err)
+		currentDir = parent
 	}
-	return os.WriteFile(output, data, 0o600)
+
+	return "", errNoSOPSConfig
 }
 
-// DecryptToString decrypts a SOPS file and returns the content as string
-func DecryptToString(input string) (string, error) {
-	if err := setAgeKeyEnv(); err != nil {
-		return "", err
-	}
+// creationRuleFor returns the first .sops.yaml creation rule matching path,
+// relative to the .sops.yaml location
+func creationRuleFor(path string) (project.CreationRule, error) {
+	var rule project.CreationRule
 
-	cmd := exec.Command("sops", "-d", input)
-	data, err := cmd.Output()
+	absPath, err := filepath.Abs(path)
 	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return "", fmt.Errorf("sops decryption failed: %s", string(exitErr.Stderr))
-		}
-		return "", fmt.Errorf("sops decryption failed: %w", err)
+		return rule, err
 	}
-	return string(data), nil
-}
 
-// EncryptString encrypts a string using SOPS and writes to output file
-func EncryptString(content string, output string) error {
-	if err := setAgeKeyEnv(); err != nil {
-		return err
-	}
-
-	// Create temporary file with content
-	tmpFile, err := os.CreateTemp("", "yoink-*.yaml")
+	sopsPath, err := findSOPSConfig(filepath.Dir(absPath))
 	if err != nil {
-		return err
+		return rule, fmt.Errorf("SOPS configuration error: %w", err)
 	}
-	defer os.Remove(tmpFile.Name())
-	defer tmpFile.Close()
 
-	if _, err := tmpFile.WriteString(content); err != nil {
-		return err
+	sopsCfg, err := project.LoadSOPSConfig(sopsPath)
+	if err != nil {
+		return rule, err
 	}
-	tmpFile.Close()
 
-	return EncryptWithSOPS(tmpFile.Name(), output)
-}
-
-// CheckSOPSConfig verifies that SOPS configuration is available
-func CheckSOPSConfig(dir string) error {
-	// Check for .sops.yaml starting from the given directory and walking up
-	currentDir, err := filepath.Abs(dir)
+	relPath, err := filepath.Rel(filepath.Dir(sopsPath), absPath)
 	if err != nil {
-		return err
+		return rule, err
 	}
-
-	for {
-		sopsPath := filepath.Join(currentDir, ".sops.yaml")
-		if _, err := os.Stat(sopsPath); err == nil {
-			return nil
-		}
-
-		parent := filepath.Dir(currentDir)
-		if parent == currentDir {
-			// Reached root
-			break
+	relPath = filepath.ToSlash(relPath)
+
+	for _, r := range sopsCfg.CreationRules {
+		if r.PathRegex != "" {
+			re, err := regexp.Compile(r.PathRegex)
+			if err != nil {
+				return rule, fmt.Errorf("invalid path_regex %q in %s: %w", r.PathRegex, sopsPath, err)
+			}
+			if !re.MatchString(relPath) {
+				continue
+			}
 		}
-		currentDir = parent
+		return r, nil
 	}
 
-	return fmt.Errorf(".sops.yaml configuration not found - run 'yoink vault-init' to set up encryption")
-}
-
-// InitSOPSForVault initializes SOPS configuration for a vault
-func InitSOPSForVault(vaultPath string, ageKeys []string) error {
-	sopsConfig := fmt.Sprintf(`creation_rules:
-  - path_regex: .*\.(yaml|yml|json)$
-    age: %s
-`, strings.Join(ageKeys, ","))
-
-	sopsPath := filepath.Join(vaultPath, ".sops.yaml")
-	return os.WriteFile(sopsPath, []byte(sopsConfig), 0o644)
+	return rule, fmt.Errorf("no creation rule in %s matches %s", sopsPath, relPath)
 }
 
 // InitSOPSForProject initializes SOPS configuration for a project (in project root)
-func InitSOPSForProject(projectPath string, ageKeys []string) error {
-	sopsConfig := fmt.Sprintf(`creation_rules:
-  - path_regex: .*\.(yaml|yml|json)$
-    age: %s
-`, strings.Join(ageKeys, ","))
-
-	sopsPath := filepath.Join(projectPath, ".sops.yaml")
-	return os.WriteFile(sopsPath, []byte(sopsConfig), 0o644)
+func InitSOPSForProject(projectPath string, defaults project.CreationRule) error {
+	return project.InitSOPSConfig(projectPath, defaults, nil)
 }
@@ -0,0 +1,439 @@
+package store
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sopsVersion is the sops format version written into new documents
+const sopsVersion = "3.8.1"
+
+// sopsNonceSize matches the 32-byte GCM nonce used by sops
+const sopsNonceSize = 32
+
+const defaultUnencryptedSuffix = "_unencrypted"
+
+var encryptedValueRe = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.+),iv:(.+),tag:(.+),type:(.+)\]`)
+
+// sopsMetadata mirrors the "sops" section of an encrypted document
+type sopsMetadata struct {
+	KeyGroups         []sopsKeyGroup `yaml:"key_groups,omitempty"`
+	Age               []ageStanza    `yaml:"age,omitempty"`
+	LastModified      string         `yaml:"lastmodified"`
+	MAC               string         `yaml:"mac"`
+	PGP               []pgpStanza    `yaml:"pgp,omitempty"`
+	Passphrase        string         `yaml:"yoink_passphrase,omitempty"`
+	UnencryptedSuffix string         `yaml:"unencrypted_suffix,omitempty"`
+	EncryptedRegex    string         `yaml:"encrypted_regex,omitempty"`
+	Version           string         `yaml:"version"`
+}
+
+// sopsKeyGroup is a group of master keys as written by sops when key_groups are configured
+type sopsKeyGroup struct {
+	Age []ageStanza `yaml:"age,omitempty"`
+	PGP []pgpStanza `yaml:"pgp,omitempty"`
+}
+
+// ageStanzas returns every age-wrapped copy of the data key, including those in key groups
+func (m sopsMetadata) ageStanzas() []ageStanza {
+	stanzas := append([]ageStanza{}, m.Age...)
+	for _, g := range m.KeyGroups {
+		stanzas = append(stanzas, g.Age...)
+	}
+	return stanzas
+}
+
+// pgpStanzas returns every pgp-wrapped copy of the data key, including those in key groups
+func (m sopsMetadata) pgpStanzas() []pgpStanza {
+	stanzas := append([]pgpStanza{}, m.PGP...)
+	for _, g := range m.KeyGroups {
+		stanzas = append(stanzas, g.PGP...)
+	}
+	return stanzas
+}
+
+// newDataKey generates a random 256-bit data key
+func newDataKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	return key, nil
+}
+
+// sealDocument encrypts every value of a plaintext YAML document with dataKey
+// and appends the given metadata, producing a sops-compatible document
+func sealDocument(plaintext []byte, dataKey []byte, meta sopsMetadata) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(plaintext, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("secrets document must be a YAML mapping")
+	}
+	if _, idx := mappingValue(root, "sops"); idx >= 0 {
+		return nil, fmt.Errorf("document is already encrypted")
+	}
+
+	if meta.UnencryptedSuffix == "" && meta.EncryptedRegex == "" {
+		meta.UnencryptedSuffix = defaultUnencryptedSuffix
+	}
+
+	w, err := newTreeWalker(dataKey, meta, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.walkMapping(root, nil); err != nil {
+		return nil, err
+	}
+
+	meta.LastModified = time.Now().UTC().Format(time.RFC3339)
+	meta.Version = sopsVersion
+
+	mac, err := encryptValue(w.sum(), dataKey, meta.LastModified, "str")
+	if err != nil {
+		return nil, err
+	}
+	meta.MAC = mac
+
+	var metaNode yaml.Node
+	if err := metaNode.Encode(meta); err != nil {
+		return nil, err
+	}
+	root.Content = append(root.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "sops"},
+		&metaNode,
+	)
+
+	return yaml.Marshal(&doc)
+}
+
+// openDocument parses an encrypted document and splits off its sops metadata
+func openDocument(ciphertext []byte) (*yaml.Node, sopsMetadata, error) {
+	var meta sopsMetadata
+	var doc yaml.Node
+	if err := yaml.Unmarshal(ciphertext, &doc); err != nil {
+		return nil, meta, fmt.Errorf("failed to parse encrypted YAML: %w", err)
+	}
+
+	if doc.Kind == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, meta, fmt.Errorf("not a sops encrypted document")
+	}
+	root := doc.Content[0]
+
+	metaNode, idx := mappingValue(root, "sops")
+	if idx < 0 {
+		return nil, meta, fmt.Errorf("sops metadata not found")
+	}
+	if err := metaNode.Decode(&meta); err != nil {
+		return nil, meta, fmt.Errorf("failed to parse sops metadata: %w", err)
+	}
+	root.Content = append(root.Content[:idx], root.Content[idx+2:]...)
+
+	return &doc, meta, nil
+}
+
+// unsealDocument decrypts a document returned by openDocument in place,
+// verifies its MAC and returns the plaintext YAML
+func unsealDocument(doc *yaml.Node, meta sopsMetadata, dataKey []byte) ([]byte, error) {
+	w, err := newTreeWalker(dataKey, meta, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.walkMapping(doc.Content[0], nil); err != nil {
+		return nil, err
+	}
+
+	lastModified, err := time.Parse(time.RFC3339, meta.LastModified)
+	if err != nil {
+		return nil, fmt.Errorf("invalid lastmodified timestamp: %w", err)
+	}
+
+	mac, _, err := decryptValue(meta.MAC, dataKey, lastModified.Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt MAC: %w", err)
+	}
+	if mac != w.sum() {
+		return nil, fmt.Errorf("MAC mismatch: secrets file may have been tampered with")
+	}
+
+	if len(doc.Content[0].Content) == 0 {
+		return []byte{}, nil
+	}
+	return yaml.Marshal(doc)
+}
+
+// mappingValue returns the value node for key in a mapping and the index of its key node
+func mappingValue(mapping *yaml.Node, key string) (*yaml.Node, int) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1], i
+		}
+	}
+	return nil, -1
+}
+
+// treeWalker encrypts or decrypts every scalar in a document while
+// accumulating the MAC over the plaintext values, in document order
+type treeWalker struct {
+	key       []byte
+	suffix    string
+	encrypted *regexp.Regexp
+	mac       hash.Hash
+	encrypt   bool
+}
+
+func newTreeWalker(key []byte, meta sopsMetadata, encrypt bool) (*treeWalker, error) {
+	w := &treeWalker{key: key, suffix: meta.UnencryptedSuffix, mac: sha512.New(), encrypt: encrypt}
+	if meta.EncryptedRegex != "" {
+		re, err := regexp.Compile(meta.EncryptedRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encrypted_regex %q: %w", meta.EncryptedRegex, err)
+		}
+		w.encrypted = re
+	}
+	return w, nil
+}
+
+func (w *treeWalker) sum() string {
+	return fmt.Sprintf("%X", w.mac.Sum(nil))
+}
+
+func (w *treeWalker) walkMapping(n *yaml.Node, path []string) error {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		keyPath := append(append([]string{}, path...), n.Content[i].Value)
+		if err := w.walkValue(n.Content[i+1], keyPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *treeWalker) walkValue(n *yaml.Node, path []string) error {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return w.walkMapping(n, path)
+	case yaml.SequenceNode:
+		for _, item := range n.Content {
+			if err := w.walkValue(item, path); err != nil {
+				return err
+			}
+		}
+		return nil
+	case yaml.ScalarNode:
+		if w.encrypt {
+			return w.encryptScalar(n, path)
+		}
+		return w.decryptScalar(n, path)
+	default:
+		return fmt.Errorf("unsupported YAML node at %s", strings.Join(path, "."))
+	}
+}
+
+// skipped reports whether a value is left in plaintext because of the
+// unencrypted suffix, or because no key on its path matches encrypted_regex
+func (w *treeWalker) skipped(path []string) bool {
+	if w.suffix != "" {
+		for _, p := range path {
+			if strings.HasSuffix(p, w.suffix) {
+				return true
+			}
+		}
+	}
+	if w.encrypted != nil {
+		for _, p := range path {
+			if w.encrypted.MatchString(p) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (w *treeWalker) encryptScalar(n *yaml.Node, path []string) error {
+	plain, typ, err := scalarPlaintext(n)
+	if err != nil {
+		return fmt.Errorf("%s: %w", strings.Join(path, "."), err)
+	}
+	w.mac.Write(macBytes(plain, typ))
+
+	if w.skipped(path) {
+		return nil
+	}
+
+	enc, err := encryptValue(string(macBytes(plain, typ)), w.key, strings.Join(path, ":")+":", typ)
+	if err != nil {
+		return err
+	}
+	n.Value, n.Tag, n.Style = enc, "!!str", 0
+	return nil
+}
+
+func (w *treeWalker) decryptScalar(n *yaml.Node, path []string) error {
+	if w.skipped(path) {
+		plain, typ, err := scalarPlaintext(n)
+		if err != nil {
+			return fmt.Errorf("%s: %w", strings.Join(path, "."), err)
+		}
+		w.mac.Write(macBytes(plain, typ))
+		return nil
+	}
+
+	plain, typ, err := decryptValue(n.Value, w.key, strings.Join(path, ":")+":")
+	if err != nil {
+		return fmt.Errorf("failed to decrypt %s: %w", strings.Join(path, "."), err)
+	}
+	if typ == "bool" {
+		// sops writes booleans as True and False
+		b, err := strconv.ParseBool(plain)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s: invalid bool %q", strings.Join(path, "."), plain)
+		}
+		plain = strconv.FormatBool(b)
+	}
+	w.mac.Write(macBytes(plain, typ))
+
+	n.Value, n.Style = plain, 0
+	switch typ {
+	case "int":
+		n.Tag = "!!int"
+	case "float":
+		n.Tag = "!!float"
+	case "bool":
+		n.Tag = "!!bool"
+	default:
+		n.Tag = "!!str"
+		if strings.Contains(plain, "\n") {
+			n.Style = yaml.LiteralStyle
+		}
+	}
+	return nil
+}
+
+// scalarPlaintext returns the canonical plaintext and sops type name of a scalar node
+func scalarPlaintext(n *yaml.Node) (string, string, error) {
+	switch n.ShortTag() {
+	case "!!int":
+		var i int
+		if err := n.Decode(&i); err != nil {
+			return "", "", err
+		}
+		return strconv.Itoa(i), "int", nil
+	case "!!float":
+		var f float64
+		if err := n.Decode(&f); err != nil {
+			return "", "", err
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), "float", nil
+	case "!!bool":
+		var b bool
+		if err := n.Decode(&b); err != nil {
+			return "", "", err
+		}
+		return strconv.FormatBool(b), "bool", nil
+	case "!!null":
+		return "", "str", nil
+	default:
+		return n.Value, "str", nil
+	}
+}
+
+// macBytes returns the bytes sops encrypts and feeds into the MAC for a
+// value, which for booleans are Python's True and False
+func macBytes(plain, typ string) []byte {
+	if typ == "bool" {
+		if plain == "true" {
+			return []byte("True")
+		}
+		return []byte("False")
+	}
+	return []byte(plain)
+}
+
+// encryptValue encrypts a single value with AES-256-GCM in the sops ENC[...] format
+func encryptValue(plain string, key []byte, additionalData, typ string) (string, error) {
+	if plain == "" {
+		return "", nil
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, sopsNonceSize)
+	if err != nil {
+		return "", err
+	}
+
+	iv := make([]byte, sopsNonceSize)
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+
+	out := gcm.Seal(nil, iv, []byte(plain), []byte(additionalData))
+	data, tag := out[:len(out)-gcm.Overhead()], out[len(out)-gcm.Overhead():]
+
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:%s]",
+		base64.StdEncoding.EncodeToString(data),
+		base64.StdEncoding.EncodeToString(iv),
+		base64.StdEncoding.EncodeToString(tag),
+		typ), nil
+}
+
+// decryptValue decrypts a sops ENC[...] value and returns its plaintext and type
+func decryptValue(value string, key []byte, additionalData string) (string, string, error) {
+	if value == "" {
+		return "", "str", nil
+	}
+
+	m := encryptedValueRe.FindStringSubmatch(value)
+	if m == nil {
+		return "", "", fmt.Errorf("value is not in sops encrypted format")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(m[1])
+	if err != nil {
+		return "", "", fmt.Errorf("invalid data: %w", err)
+	}
+	iv, err := base64.StdEncoding.DecodeString(m[2])
+	if err != nil {
+		return "", "", fmt.Errorf("invalid iv: %w", err)
+	}
+	tag, err := base64.StdEncoding.DecodeString(m[3])
+	if err != nil {
+		return "", "", fmt.Errorf("invalid tag: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", "", err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return "", "", err
+	}
+
+	plain, err := gcm.Open(nil, iv, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		return "", "", fmt.Errorf("authentication failed: %w", err)
+	}
+
+	return string(plain), m[4], nil
+}
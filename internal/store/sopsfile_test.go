@@ -0,0 +1,243 @@
+package store
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The fixtures in testdata were encrypted by the sops 3.9.4 CLI from
+// sops_plain.yaml with the age key in sops_age_key.txt:
+//
+//	sops encrypt --age <recipient> sops_plain.yaml > sops_age.enc.yaml
+//	sops encrypt --age <recipient> --encrypted-regex '^(API_KEY|password)$' \
+//	    sops_plain.yaml > sops_age_regex.enc.yaml
+const testKeyFile = "testdata/sops_age_key.txt"
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// assertSameYAML compares two YAML documents by value, ignoring layout
+func assertSameYAML(t *testing.T, got, want []byte) {
+	t.Helper()
+	var g, w interface{}
+	if err := yaml.Unmarshal(got, &g); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	if err := yaml.Unmarshal(want, &w); err != nil {
+		t.Fatalf("unmarshal expected: %v", err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		t.Fatalf("documents differ\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDecryptSOPSFixtures(t *testing.T) {
+	want := readTestdata(t, "sops_plain.yaml")
+	enc := &AgeEncryptor{KeyFile: testKeyFile}
+
+	for _, name := range []string{"sops_age.enc.yaml", "sops_age_regex.enc.yaml"} {
+		t.Run(name, func(t *testing.T) {
+			plaintext, err := enc.Decrypt(readTestdata(t, name))
+			if err != nil {
+				t.Fatalf("Decrypt: %v", err)
+			}
+			assertSameYAML(t, plaintext, want)
+		})
+	}
+}
+
+func TestSealUnsealRoundTrip(t *testing.T) {
+	plaintext := readTestdata(t, "sops_plain.yaml")
+	key, err := newDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := sealDocument(plaintext, key, sopsMetadata{})
+	if err != nil {
+		t.Fatalf("sealDocument: %v", err)
+	}
+	for _, secret := range []string{"s3cr3t", "hunter2", "db.internal", "BEGIN CERTIFICATE", "a.example.com"} {
+		if bytes.Contains(sealed, []byte(secret)) {
+			t.Errorf("sealed document contains %q in plaintext", secret)
+		}
+	}
+	if !bytes.Contains(sealed, []byte("region_unencrypted: eu-west-1")) {
+		t.Errorf("value with the unencrypted suffix was encrypted:\n%s", sealed)
+	}
+
+	doc, meta, err := openDocument(sealed)
+	if err != nil {
+		t.Fatalf("openDocument: %v", err)
+	}
+	if meta.UnencryptedSuffix != defaultUnencryptedSuffix || meta.Version != sopsVersion {
+		t.Errorf("metadata = %+v", meta)
+	}
+	opened, err := unsealDocument(doc, meta, key)
+	if err != nil {
+		t.Fatalf("unsealDocument: %v", err)
+	}
+	assertSameYAML(t, opened, plaintext)
+}
+
+func TestSealEncryptedRegex(t *testing.T) {
+	plaintext := readTestdata(t, "sops_plain.yaml")
+	key, _ := newDataKey()
+
+	sealed, err := sealDocument(plaintext, key, sopsMetadata{EncryptedRegex: "^(API_KEY|password)$"})
+	if err != nil {
+		t.Fatalf("sealDocument: %v", err)
+	}
+	for _, plain := range []string{"host: db.internal", "PORT: 8080"} {
+		if !bytes.Contains(sealed, []byte(plain)) {
+			t.Errorf("%q should stay in plaintext:\n%s", plain, sealed)
+		}
+	}
+	for _, secret := range []string{"s3cr3t", "hunter2"} {
+		if bytes.Contains(sealed, []byte(secret)) {
+			t.Errorf("%q matches encrypted_regex but was left in plaintext", secret)
+		}
+	}
+
+	doc, meta, err := openDocument(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.UnencryptedSuffix != "" {
+		t.Errorf("unencrypted_suffix = %q, want none alongside encrypted_regex", meta.UnencryptedSuffix)
+	}
+	opened, err := unsealDocument(doc, meta, key)
+	if err != nil {
+		t.Fatalf("unsealDocument: %v", err)
+	}
+	assertSameYAML(t, opened, plaintext)
+}
+
+func TestUnsealDetectsTampering(t *testing.T) {
+	key, _ := newDataKey()
+	sealed, err := sealDocument([]byte("A: one\nB: two\nnote_unencrypted: hi\n"), key, sopsMetadata{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unseal := func(data []byte, key []byte) error {
+		doc, meta, err := openDocument(data)
+		if err != nil {
+			return err
+		}
+		_, err = unsealDocument(doc, meta, key)
+		return err
+	}
+
+	t.Run("unencrypted value changed", func(t *testing.T) {
+		tampered := bytes.Replace(sealed, []byte("note_unencrypted: hi"), []byte("note_unencrypted: bye"), 1)
+		err := unseal(tampered, key)
+		if err == nil || !strings.Contains(err.Error(), "MAC mismatch") {
+			t.Fatalf("err = %v, want a MAC mismatch", err)
+		}
+	})
+
+	t.Run("encrypted values swapped", func(t *testing.T) {
+		var doc map[string]interface{}
+		if err := yaml.Unmarshal(sealed, &doc); err != nil {
+			t.Fatal(err)
+		}
+		a, b := doc["A"].(string), doc["B"].(string)
+		tampered := strings.NewReplacer(a, b, b, a).Replace(string(sealed))
+		if err := unseal([]byte(tampered), key); err == nil {
+			t.Fatal("values moved to another key decrypted without error")
+		}
+	})
+
+	t.Run("wrong data key", func(t *testing.T) {
+		other, _ := newDataKey()
+		if err := unseal(sealed, other); err == nil {
+			t.Fatal("document decrypted with the wrong data key")
+		}
+	})
+}
+
+func TestAgeEncryptorRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	recipient, err := AgePublicKey(testKeyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sopsYAML := "creation_rules:\n  - path_regex: .*\n    age: " + recipient + "\n"
+	if err := os.WriteFile(filepath.Join(dir, ".sops.yaml"), []byte(sopsYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	enc := &AgeEncryptor{KeyFile: testKeyFile}
+	plaintext := readTestdata(t, "sops_plain.yaml")
+	sealed, err := enc.Encrypt(plaintext, filepath.Join(dir, "secrets.enc.yaml"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	opened, err := enc.Decrypt(sealed)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	assertSameYAML(t, opened, plaintext)
+}
+
+func TestPassphraseEncryptor(t *testing.T) {
+	dir := t.TempDir()
+	sopsYAML := "creation_rules:\n  - path_regex: .*\n    encrypted_regex: ^(API_KEY|password)$\n"
+	if err := os.WriteFile(filepath.Join(dir, ".sops.yaml"), []byte(sopsYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "secrets.enc.yaml")
+	plaintext := readTestdata(t, "sops_plain.yaml")
+
+	// A mistyped confirmation refuses to create the file
+	enc := &PassphraseEncryptor{
+		Passphrase: func() (string, error) { return "correct horse", nil },
+		Confirm:    func() (string, error) { return "correct hose", nil },
+	}
+	if _, err := enc.Encrypt(plaintext, path); err == nil {
+		t.Fatal("Encrypt succeeded with a mismatched confirmation")
+	}
+
+	confirmed := 0
+	enc.Confirm = func() (string, error) { confirmed++; return "correct horse", nil }
+	sealed, err := enc.Encrypt(plaintext, path)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if confirmed != 1 {
+		t.Errorf("confirmed %d times creating a file, want 1", confirmed)
+	}
+	if !bytes.Contains(sealed, []byte("host: db.internal")) || bytes.Contains(sealed, []byte("s3cr3t")) {
+		t.Errorf("encrypted_regex from .sops.yaml not applied:\n%s", sealed)
+	}
+	if err := os.WriteFile(path, sealed, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rewriting an existing file takes the passphrase once
+	enc = &PassphraseEncryptor{
+		Passphrase: func() (string, error) { return "correct horse", nil },
+		Confirm:    func() (string, error) { t.Error("asked to confirm the passphrase of an existing file"); return "", nil },
+	}
+	opened, err := enc.Decrypt(sealed)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	assertSameYAML(t, opened, plaintext)
+	if _, err := enc.Encrypt(opened, path); err != nil {
+		t.Fatalf("Encrypt existing file: %v", err)
+	}
+}
@@ -4,30 +4,35 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
+	"time"
 
 	"github.com/jack-kitto/yoink/internal/util"
-	"gopkg.in/yaml.v3"
 )
 
 type Store struct {
-	Path   string
-	data   map[string]string
-	dryRun bool
+	Path    string
+	enc     Encryptor
+	data    map[string]string
+	history map[string][]HistoryEntry
+	dryRun  bool
 }
 
-func New(path string) *Store {
+func New(path string, enc Encryptor) *Store {
 	return &Store{
-		Path: path,
-		data: make(map[string]string),
+		Path:    path,
+		enc:     enc,
+		data:    make(map[string]string),
+		history: make(map[string][]HistoryEntry),
 	}
 }
 
-func NewWithDryRun(path string, dryRun bool) *Store {
+func NewWithDryRun(path string, enc Encryptor, dryRun bool) *Store {
 	return &Store{
-		Path:   path,
-		data:   make(map[string]string),
-		dryRun: dryRun,
+		Path:    path,
+		enc:     enc,
+		data:    make(map[string]string),
+		history: make(map[string][]HistoryEntry),
+		dryRun:  dryRun,
 	}
 }
 
@@ -47,54 +52,29 @@ func (s *Store) load() error {
 		return nil
 	}
 
-	// Check for SOPS config before attempting to decrypt
-	dir := filepath.Dir(s.Path)
-	if err := CheckSOPSConfig(dir); err != nil {
-		return fmt.Errorf("SOPS configuration error: %w", err)
-	}
-
-	// Create temporary file for decrypted content
-	tmpFile, err := os.CreateTemp("", "yoink-decrypt-*.yaml")
-	if err != nil {
-		return err
-	}
-	defer os.Remove(tmpFile.Name())
-	defer tmpFile.Close()
-
-	// Decrypt the file - don't silently ignore errors
-	if err := DecryptWithSOPS(s.Path, tmpFile.Name()); err != nil {
-		// Check if the file is actually empty/corrupted
-		if fileInfo, statErr := os.Stat(s.Path); statErr == nil && fileInfo.Size() == 0 {
-			fmt.Printf("⚠️  Warning: secrets file is empty\n")
-			s.data = make(map[string]string)
-			return nil
-		}
-		return fmt.Errorf("failed to decrypt secrets file %s: %w", s.Path, err)
-	}
-
-	// Read decrypted content
-	data, err := os.ReadFile(tmpFile.Name())
+	ciphertext, err := os.ReadFile(s.Path)
 	if err != nil {
 		return err
 	}
 
-	// Handle empty decrypted content
-	if len(data) == 0 {
+	// Check if the file is actually empty
+	if len(ciphertext) == 0 {
+		fmt.Printf("⚠️  Warning: secrets file is empty\n")
 		s.data = make(map[string]string)
 		return nil
 	}
 
-	// Parse YAML
-	var yamlData map[string]interface{}
-	if err := yaml.Unmarshal(data, &yamlData); err != nil {
-		return fmt.Errorf("failed to parse decrypted YAML: %w", err)
+	// Decrypt in memory - plaintext never touches disk
+	data, err := s.enc.Decrypt(ciphertext)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt secrets file %s: %w", s.Path, err)
 	}
 
-	// Convert to string map
-	s.data = make(map[string]string)
-	for k, v := range yamlData {
-		s.data[k] = fmt.Sprintf("%v", v)
+	secrets, history, err := parseSecrets(data)
+	if err != nil {
+		return err
 	}
+	s.data, s.history = secrets, history
 
 	return nil
 }
@@ -109,34 +89,18 @@ func (s *Store) save() error {
 		return err
 	}
 
-	// Check for SOPS config
-	dir := filepath.Dir(s.Path)
-	if err := CheckSOPSConfig(dir); err != nil {
-		return fmt.Errorf("SOPS configuration error: %w", err)
-	}
-
 	// Convert data to YAML
-	yamlData, err := yaml.Marshal(s.data)
+	yamlData, err := marshalSecrets(s.data, s.history)
 	if err != nil {
 		return err
 	}
 
-	// Create temporary file for plaintext
-	tmpFile, err := os.CreateTemp("", "yoink-encrypt-*.yaml")
+	// Encrypt in memory and save
+	encrypted, err := s.enc.Encrypt(yamlData, s.Path)
 	if err != nil {
 		return err
 	}
-	defer os.Remove(tmpFile.Name())
-	defer tmpFile.Close()
-
-	// Write plaintext to temp file
-	if _, err := tmpFile.Write(yamlData); err != nil {
-		return err
-	}
-	tmpFile.Close()
-
-	// Encrypt and save
-	return EncryptWithSOPS(tmpFile.Name(), s.Path)
+	return os.WriteFile(s.Path, encrypted, 0o600)
 }
 
 func (s *Store) Load() error {
@@ -144,8 +108,11 @@ func (s *Store) Load() error {
 }
 
 func (s *Store) Set(key, value string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
 	if s.dryRun {
-		fmt.Printf("🔍 [DRY RUN] Would set %s = %s\n", key, value)
+		fmt.Printf("🔍 [DRY RUN] Would set %s (%d bytes)\n", key, len(value))
 		return nil
 	}
 
@@ -158,6 +125,87 @@ func (s *Store) Set(key, value string) error {
 	return s.save()
 }
 
+// Rotate replaces a secret's value, keeping the previous value in the
+// secret's history until grace has passed
+func (s *Store) Rotate(key, value string, grace time.Duration) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	if s.dryRun {
+		fmt.Printf("🔍 [DRY RUN] Would rotate %s\n", key)
+		return nil
+	}
+
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	if previous, exists := s.data[key]; exists {
+		now := time.Now()
+		s.history[key] = append(s.history[key], HistoryEntry{
+			Value:     previous,
+			RotatedAt: now.Format(time.RFC3339),
+			ExpiresAt: now.Add(grace).Format(time.RFC3339),
+		})
+	}
+
+	s.data[key] = value
+	return s.save()
+}
+
+// Replace overwrites every secret in the file with data
+func (s *Store) Replace(data map[string]string) error {
+	for k := range data {
+		if err := ValidateKey(k); err != nil {
+			return err
+		}
+	}
+	if s.dryRun {
+		fmt.Printf("🔍 [DRY RUN] Would write %d secrets to %s\n", len(data), s.Path)
+		return nil
+	}
+
+	s.data = make(map[string]string)
+	for k, v := range data {
+		s.data[k] = v
+	}
+	for k := range s.history {
+		if _, exists := s.data[k]; !exists {
+			delete(s.history, k)
+		}
+	}
+	return s.save()
+}
+
+// Reencrypt decrypts the file and encrypts it again with a fresh data key
+// for the recipients .sops.yaml currently configures for it. The document is
+// passed through as it is, so values yoink doesn't manage, nested ones
+// included, survive
+func (s *Store) Reencrypt() error {
+	if s.dryRun {
+		fmt.Printf("🔍 [DRY RUN] Would re-encrypt %s\n", s.Path)
+		return nil
+	}
+
+	ciphertext, err := os.ReadFile(s.Path)
+	if err != nil {
+		return err
+	}
+	if len(ciphertext) == 0 {
+		return nil
+	}
+
+	plaintext, err := s.enc.Decrypt(ciphertext)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt secrets file %s: %w", s.Path, err)
+	}
+	encrypted, err := s.enc.Encrypt(plaintext, s.Path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.Path, encrypted, 0o600)
+}
+
 func (s *Store) Get(key string) (string, error) {
 	if err := s.load(); err != nil {
 		return "", err
@@ -170,6 +218,15 @@ func (s *Store) Get(key string) (string, error) {
 	return value, nil
 }
 
+// Previous returns a rotated secret's previous value while it is within its
+// grace period
+func (s *Store) Previous(key string) (string, error) {
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	return previousValue(s.history, key)
+}
+
 func (s *Store) Delete(key string) error {
 	if s.dryRun {
 		fmt.Printf("🔍 [DRY RUN] Would delete secret: %s\n", key)
@@ -185,6 +242,7 @@ func (s *Store) Delete(key string) error {
 	}
 
 	delete(s.data, key)
+	delete(s.history, key)
 	return s.save()
 }
 
@@ -227,9 +285,9 @@ func (s *Store) ExportEnv() (string, error) {
 		return "", err
 	}
 
-	var sb strings.Builder
-	for k, v := range data {
-		sb.WriteString(fmt.Sprintf("%s=%s\n", k, v))
+	out, err := Serialize(data, FormatDotenv)
+	if err != nil {
+		return "", err
 	}
-	return sb.String(), nil
+	return string(out), nil
 }
@@ -0,0 +1,118 @@
+package store
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jack-kitto/yoink/internal/util"
+)
+
+// plainEncryptor stores documents unencrypted, for tests of the store logic
+type plainEncryptor struct{}
+
+func (plainEncryptor) Encrypt(plaintext []byte, path string) ([]byte, error) { return plaintext, nil }
+func (plainEncryptor) Decrypt(ciphertext []byte) ([]byte, error)             { return ciphertext, nil }
+
+func TestSetRefusesReservedKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc.yaml")
+	s := New(path, plainEncryptor{})
+
+	for _, key := range []string{HistoryKey, "sops", ""} {
+		if err := s.Set(key, "x"); err == nil {
+			t.Errorf("Set(%q) succeeded, want an error", key)
+		}
+	}
+	if util.FileExists(path) {
+		t.Fatalf("secrets file was written for a reserved key")
+	}
+
+	// The vault stays readable after a refused write
+	if err := s.Set("API_KEY", "x"); err != nil {
+		t.Fatalf("Set(API_KEY): %v", err)
+	}
+	if err := s.Rotate(HistoryKey, "y", 0); err == nil {
+		t.Errorf("Rotate(%q) succeeded, want an error", HistoryKey)
+	}
+	if got, err := s.Get("API_KEY"); err != nil || got != "x" {
+		t.Fatalf("Get(API_KEY) = %q, %v; want x", got, err)
+	}
+}
+
+func TestBatchesRefuseReservedKeys(t *testing.T) {
+	if _, err := ParseAssignments([]string{"A=1", HistoryKey + "=x"}); err == nil {
+		t.Errorf("ParseAssignments accepted %s", HistoryKey)
+	}
+	if _, err := ParseChanges([]byte("set:\n  sops: x\n")); err == nil {
+		t.Errorf("ParseChanges accepted sops")
+	}
+	if _, err := DecodeYAML([]byte(HistoryKey + ": x\n")); err == nil {
+		t.Errorf("DecodeYAML accepted %s", HistoryKey)
+	}
+
+	s := New(filepath.Join(t.TempDir(), "secrets.enc.yaml"), plainEncryptor{})
+	if err := s.Apply([]Change{{Key: "A", Value: "1"}, {Key: "sops", Value: "x"}}); err == nil {
+		t.Errorf("Apply accepted sops")
+	}
+	if err := s.Replace(map[string]string{HistoryKey: "x"}); err == nil {
+		t.Errorf("Replace accepted %s", HistoryKey)
+	}
+}
+
+func TestReencryptKeepsDocument(t *testing.T) {
+	dir := t.TempDir()
+	recipient, err := AgePublicKey(testKeyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sopsYAML := "creation_rules:\n  - path_regex: .*\n    age: " + recipient + "\n"
+	if err := os.WriteFile(filepath.Join(dir, ".sops.yaml"), []byte(sopsYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The fixture has nested values, which yoink's own views flatten
+	enc := &AgeEncryptor{KeyFile: testKeyFile}
+	path := filepath.Join(dir, "secrets.enc.yaml")
+	plaintext := readTestdata(t, "sops_plain.yaml")
+	sealed, err := enc.Encrypt(plaintext, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, sealed, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := New(path, enc).Reencrypt(); err != nil {
+		t.Fatalf("Reencrypt: %v", err)
+	}
+	rekeyed, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(rekeyed, sealed) {
+		t.Fatal("file was not re-encrypted")
+	}
+	opened, err := enc.Decrypt(rekeyed)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	assertSameYAML(t, opened, plaintext)
+}
+
+func TestValidateRecipient(t *testing.T) {
+	recipient, err := AgePublicKey(testKeyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{recipient, "85D77543B3D624B63CEA9E6DBC17301B491B3F21"} {
+		if err := ValidateRecipient(key); err != nil {
+			t.Errorf("ValidateRecipient(%q): %v", key, err)
+		}
+	}
+	for _, key := range []string{"", "age1notakey", recipient[:len(recipient)-1], "bob@example.com", "85D77543B3D624B6"} {
+		if err := ValidateRecipient(key); err == nil {
+			t.Errorf("ValidateRecipient(%q) accepted it", key)
+		}
+	}
+}
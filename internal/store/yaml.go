@@ -0,0 +1,63 @@
+package store
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EncodeYAML writes secrets as a plaintext YAML mapping with sorted keys
+func EncodeYAML(data map[string]string) ([]byte, error) {
+	if len(data) == 0 {
+		return []byte{}, nil
+	}
+	return yaml.Marshal(data)
+}
+
+// DecodeYAML parses a plaintext YAML mapping of secrets, such as one edited
+// by hand, rejecting nested values and yoink's reserved keys
+func DecodeYAML(plaintext []byte) (map[string]string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(plaintext, &doc); err != nil {
+		return nil, err
+	}
+
+	if len(doc.Content) == 0 {
+		return make(map[string]string), nil
+	}
+
+	return decodeMapping(doc.Content[0])
+}
+
+// decodeMapping converts a YAML mapping node of scalar values into secrets
+func decodeMapping(root *yaml.Node) (map[string]string, error) {
+	data := make(map[string]string)
+	if root.Kind == yaml.ScalarNode && root.ShortTag() == "!!null" {
+		return data, nil
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: expected a mapping of KEY: value", root.Line)
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+
+		if err := ValidateKey(key.Value); err != nil {
+			return nil, fmt.Errorf("line %d: %w", key.Line, err)
+		}
+		if _, exists := data[key.Value]; exists {
+			return nil, fmt.Errorf("line %d: duplicate key %s", key.Line, key.Value)
+		}
+		if value.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("line %d: value of %s must be a string, not a nested structure", value.Line, key.Value)
+		}
+
+		text, err := nodeText(value)
+		if err != nil {
+			return nil, err
+		}
+		data[key.Value] = text
+	}
+
+	return data, nil
+}
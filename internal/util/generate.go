@@ -0,0 +1,106 @@
+package util
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const (
+	alphanumeric = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	symbols      = "!#%+-.:=@^_~"
+
+	passwordLength = 24
+)
+
+// GenerateSecret generates a random secret value from a generator spec:
+// random:N (N alphanumeric characters), hex:N (N hex characters), uuid
+// (random UUIDv4) or password (mixed-class password)
+func GenerateSecret(spec string) (string, error) {
+	name, arg, hasArg := strings.Cut(spec, ":")
+
+	length := 0
+	if hasArg {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n <= 0 {
+			return "", fmt.Errorf("invalid length %q in generator %q", arg, spec)
+		}
+		length = n
+	}
+
+	switch name {
+	case "random":
+		if length == 0 {
+			length = 32
+		}
+		return randomString(alphanumeric, length)
+	case "hex":
+		if length == 0 {
+			length = 64
+		}
+		buf := make([]byte, (length+1)/2)
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(buf)[:length], nil
+	case "uuid":
+		if hasArg {
+			return "", fmt.Errorf("generator uuid takes no length")
+		}
+		return randomUUID()
+	case "password":
+		if length == 0 {
+			length = passwordLength
+		}
+		return randomPassword(length)
+	default:
+		return "", fmt.Errorf("unknown generator %q (expected random:N, hex:N, uuid or password)", spec)
+	}
+}
+
+func randomString(charset string, length int) (string, error) {
+	out := make([]byte, length)
+	max := big.NewInt(int64(len(charset)))
+	for i := range out {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		out[i] = charset[n.Int64()]
+	}
+	return string(out), nil
+}
+
+func randomUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// randomPassword generates a password containing at least one upper-case
+// letter, lower-case letter, digit and symbol
+func randomPassword(length int) (string, error) {
+	if length < 4 {
+		return "", fmt.Errorf("password length must be at least 4")
+	}
+
+	for {
+		pw, err := randomString(alphanumeric+symbols, length)
+		if err != nil {
+			return "", err
+		}
+		if strings.ContainsAny(pw, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") &&
+			strings.ContainsAny(pw, "abcdefghijklmnopqrstuvwxyz") &&
+			strings.ContainsAny(pw, "0123456789") &&
+			strings.ContainsAny(pw, symbols) {
+			return pw, nil
+		}
+	}
+}
@@ -0,0 +1,10 @@
+package util
+
+import (
+	"net/http"
+	"time"
+)
+
+// HTTPClient is shared by everything yoink fetches over HTTPS, so
+// connections are reused and a stalled server can't hang a command
+var HTTPClient = &http.Client{Timeout: 30 * time.Second}
@@ -0,0 +1,54 @@
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// PromptHidden reads a line from the terminal without echoing it
+func PromptHidden(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("cannot prompt for input: stdin is not a terminal")
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	data, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// PromptConfirm asks a yes/no question on the terminal, returning def when
+// the answer is empty and false when stdin is not a terminal
+func PromptConfirm(prompt string, def bool) bool {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return false
+	}
+
+	choices := "[y/N]"
+	if def {
+		choices = "[Y/n]"
+	}
+	fmt.Fprintf(os.Stderr, "%s %s ", prompt, choices)
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return def
+	}
+
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true
+	case "n", "no":
+		return false
+	default:
+		return def
+	}
+}
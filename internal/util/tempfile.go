@@ -0,0 +1,63 @@
+package util
+
+import (
+	"os"
+)
+
+// privateTempDir returns a memory-backed directory for short-lived
+// plaintext files when one is available, falling back to the system temp dir
+func privateTempDir() string {
+	for _, dir := range []string{os.Getenv("XDG_RUNTIME_DIR"), "/dev/shm"} {
+		if dir == "" {
+			continue
+		}
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir
+		}
+	}
+	return os.TempDir()
+}
+
+// CreatePrivateTemp creates a 0600 temporary file, preferring memory-backed
+// storage so plaintext is less likely to reach disk
+func CreatePrivateTemp(pattern string) (*os.File, error) {
+	f, err := os.CreateTemp(privateTempDir(), pattern)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Chmod(0o600); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return f, nil
+}
+
+// ShredFile overwrites a file with zeros before removing it
+func ShredFile(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if f, err := os.OpenFile(path, os.O_WRONLY, 0); err == nil {
+		zeros := make([]byte, 4096)
+		for remaining := info.Size(); remaining > 0; {
+			n := int64(len(zeros))
+			if remaining < n {
+				n = remaining
+			}
+			if _, err := f.Write(zeros[:n]); err != nil {
+				break
+			}
+			remaining -= n
+		}
+		f.Sync()
+		f.Close()
+	}
+
+	return os.Remove(path)
+}
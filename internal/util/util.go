@@ -10,7 +10,7 @@ import (
 
 // CheckDependencies verifies that required external tools are available
 func CheckDependencies() error {
-	deps := []string{"sops", "age", "gh", "git"}
+	deps := []string{"git"}
 	missing := []string{}
 
 	for _, dep := range deps {
@@ -20,7 +20,7 @@ func CheckDependencies() error {
 	}
 
 	if len(missing) > 0 {
-		return fmt.Errorf("missing required tools: %s\n\nPlease install:\n- sops: https://github.com/mozilla/sops\n- age: https://github.com/FiloSottile/age\n- gh: https://cli.github.com\n- git: https://git-scm.com",
+		return fmt.Errorf("missing required tools: %s\n\nPlease install:\n- git: https://git-scm.com",
 			strings.Join(missing, ", "))
 	}
 
@@ -0,0 +1,323 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Offline keeps yoink from contacting vault remotes; reads are served from
+// the cache and anything that needs the remote fails
+var Offline bool
+
+// ErrNotCached is returned for commits or files the cache doesn't hold
+var ErrNotCached = errors.New("not in the local vault cache")
+
+const (
+	cacheStateFile = "state.yaml"
+	cacheRefsFile  = "refs.yaml"
+	completeMarker = ".complete"
+	mirrorDir      = "repo.git"
+
+	// keepSnapshots is how many commits the cache holds per vault
+	keepSnapshots = 5
+)
+
+// Cache keeps still-encrypted copies of vault files, keyed by the commit they
+// were read at, so reads keep working without network access. A snapshot is
+// complete when it holds the whole vault tree (after a clone) and partial
+// when it only holds the files fetched over HTTPS
+type Cache struct {
+	Dir string
+}
+
+// cacheState records the most recent commit the cache has seen
+type cacheState struct {
+	Head      string    `yaml:"head"`
+	UpdatedAt time.Time `yaml:"updated_at"`
+}
+
+// RefState records the commit a branch or tag pointed at when it was last
+// looked up, and the ETag of that answer for conditional requests
+type RefState struct {
+	Commit    string    `yaml:"commit"`
+	ETag      string    `yaml:"etag,omitempty"`
+	UpdatedAt time.Time `yaml:"updated_at"`
+}
+
+// Snapshot describes the cached files of one commit
+type Snapshot struct {
+	Commit   string
+	Files    int
+	Complete bool
+	Updated  time.Time
+}
+
+// OpenCache returns the cache of a vault repository
+func OpenCache(repoURL string) (*Cache, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &Cache{Dir: filepath.Join(dir, "yoink", "vaults", sanitizeRepoName(repoURL))}, nil
+}
+
+// MirrorDir is where a bare mirror of the vault repository is kept, which
+// working copies are cloned from
+func (c *Cache) MirrorDir() string {
+	return filepath.Join(c.Dir, mirrorDir)
+}
+
+// Latest returns the most recent commit the cache holds and when it was
+// cached
+func (c *Cache) Latest() (string, time.Time, error) {
+	data, err := os.ReadFile(filepath.Join(c.Dir, cacheStateFile))
+	if os.IsNotExist(err) {
+		return "", time.Time{}, ErrNotCached
+	}
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	var state cacheState
+	if err := yaml.Unmarshal(data, &state); err != nil {
+		return "", time.Time{}, fmt.Errorf("corrupt cache state in %s: %w", c.Dir, err)
+	}
+	if state.Head == "" {
+		return "", time.Time{}, ErrNotCached
+	}
+	return state.Head, state.UpdatedAt, nil
+}
+
+// ReadFile returns a vault file as it was at a commit. It returns
+// fs.ErrNotExist when a complete snapshot shows the vault has no such file,
+// and ErrNotCached when the cache can't tell
+func (c *Cache) ReadFile(commit, file string) ([]byte, error) {
+	dir := filepath.Join(c.Dir, commit)
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+	if err == nil {
+		return data, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, completeMarker)); err == nil {
+		return nil, fs.ErrNotExist
+	}
+	return nil, ErrNotCached
+}
+
+// Put caches a single file fetched at a commit
+func (c *Cache) Put(commit, file string, data []byte) error {
+	path := filepath.Join(c.Dir, commit, filepath.FromSlash(file))
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// Store caches the whole vault tree checked out in repoDir at a commit,
+// which becomes the latest
+func (c *Cache) Store(commit, repoDir string) error {
+	dir := filepath.Join(c.Dir, commit)
+	if _, err := os.Stat(filepath.Join(dir, completeMarker)); err == nil {
+		return c.SetLatest(commit)
+	}
+
+	// Copy into a staging directory so a snapshot is only ever seen whole
+	tmp := dir + ".tmp"
+	os.RemoveAll(tmp)
+	err := filepath.WalkDir(repoDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(repoDir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(tmp, rel)
+		if err := os.MkdirAll(filepath.Dir(dest), 0o700); err != nil {
+			return err
+		}
+		return os.WriteFile(dest, data, 0o600)
+	})
+	if err == nil {
+		err = os.WriteFile(filepath.Join(tmp, completeMarker), nil, 0o600)
+	}
+	if err == nil {
+		os.RemoveAll(dir)
+		err = os.Rename(tmp, dir)
+	}
+	if err != nil {
+		os.RemoveAll(tmp)
+		return fmt.Errorf("failed to cache vault: %w", err)
+	}
+
+	return c.SetLatest(commit)
+}
+
+// SetLatest records commit as the vault's current default branch, which
+// offline reads are served from
+func (c *Cache) SetLatest(commit string) error {
+	if err := os.MkdirAll(c.Dir, 0o700); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(cacheState{Head: commit, UpdatedAt: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(c.Dir, cacheStateFile), data, 0o600); err != nil {
+		return err
+	}
+	return c.prune(commit)
+}
+
+// Ref returns what a branch or tag ("HEAD" for the default branch) pointed
+// at when it was last looked up, or ErrNotCached
+func (c *Cache) Ref(name string) (RefState, error) {
+	refs, err := c.refs()
+	if err != nil {
+		return RefState{}, err
+	}
+	state, ok := refs[name]
+	if !ok || state.Commit == "" {
+		return RefState{}, ErrNotCached
+	}
+	return state, nil
+}
+
+// SetRef records what a branch or tag points at
+func (c *Cache) SetRef(name string, state RefState) error {
+	// A corrupt refs file only costs a lookup, so start over
+	refs, _ := c.refs()
+	if refs == nil {
+		refs = make(map[string]RefState)
+	}
+	refs[name] = state
+
+	if err := os.MkdirAll(c.Dir, 0o700); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(refs)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(c.Dir, cacheRefsFile), data, 0o600)
+}
+
+func (c *Cache) refs() (map[string]RefState, error) {
+	data, err := os.ReadFile(filepath.Join(c.Dir, cacheRefsFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var refs map[string]RefState
+	if err := yaml.Unmarshal(data, &refs); err != nil {
+		return nil, fmt.Errorf("corrupt cache refs in %s: %w", c.Dir, err)
+	}
+	return refs, nil
+}
+
+// LatestComplete returns the most recently updated snapshot holding the whole
+// vault tree, or ErrNotCached. Complete snapshots come from clones of the
+// default branch, so this is the newest commit the cache can answer for any
+// file when the latest snapshot is partial
+func (c *Cache) LatestComplete() (Snapshot, error) {
+	snaps, err := c.Snapshots()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	for _, s := range snaps {
+		if s.Complete {
+			return s, nil
+		}
+	}
+	return Snapshot{}, ErrNotCached
+}
+
+// Snapshots lists the cached commits, most recently updated first
+func (c *Cache) Snapshots() ([]Snapshot, error) {
+	entries, err := os.ReadDir(c.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snaps []Snapshot
+	for _, e := range entries {
+		if !e.IsDir() || filepath.Ext(e.Name()) == ".tmp" || e.Name() == mirrorDir {
+			continue
+		}
+		snap := Snapshot{Commit: e.Name()}
+		dir := filepath.Join(c.Dir, e.Name())
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			if d.Name() == completeMarker {
+				snap.Complete = true
+				return nil
+			}
+			snap.Files++
+			if info, err := d.Info(); err == nil && info.ModTime().After(snap.Updated) {
+				snap.Updated = info.ModTime()
+			}
+			return nil
+		})
+		snaps = append(snaps, snap)
+	}
+
+	sort.Slice(snaps, func(i, j int) bool { return snaps[i].Updated.After(snaps[j].Updated) })
+	return snaps, nil
+}
+
+// prune drops the oldest snapshots beyond keepSnapshots, always keeping the latest
+func (c *Cache) prune(latest string) error {
+	snaps, err := c.Snapshots()
+	if err != nil {
+		return err
+	}
+
+	kept := 1
+	for _, s := range snaps {
+		if s.Commit == latest {
+			continue
+		}
+		if kept < keepSnapshots {
+			kept++
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(c.Dir, s.Commit)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Clear removes everything cached for the vault
+func (c *Cache) Clear() error {
+	return os.RemoveAll(c.Dir)
+}
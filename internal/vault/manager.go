@@ -2,12 +2,14 @@ package vault
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/jack-kitto/yoink/internal/git"
 	"github.com/jack-kitto/yoink/internal/util"
 )
 
@@ -60,6 +62,10 @@ func (m *Manager) quietRun(dir string, args ...string) error {
 }
 
 func (m *Manager) Sync() error {
+	if Offline {
+		return fmt.Errorf("can't reach the vault with --offline (only reads from the cache work; run 'yoink cache refresh' while online)")
+	}
+
 	// If repo exists, pull latest; else clone fresh
 	dir := filepath.Join(m.WorkDir, "repo")
 	if _, err := os.Stat(dir); err == nil {
@@ -81,24 +87,81 @@ func (m *Manager) Sync() error {
 			fmt.Println("🌀 Syncing vault...")
 		}
 
-		if err := m.quietRun(m.WorkDir, "git", "clone", m.RepoURL, "repo"); err != nil {
+		if err := m.clone(); err != nil {
 			return fmt.Errorf("failed to clone vault: %w", err)
 		}
 	}
 
+	// Keep an encrypted copy for offline reads
+	if err := m.cacheSnapshot(); err != nil && m.Verbose {
+		fmt.Printf("⚠️  Could not cache vault: %v\n", err)
+	}
+
 	return nil
 }
 
+// clone checks the vault out into the working directory from a mirror kept
+// in the cache, so only the commits made since the last sync are fetched.
+// Without a usable mirror it clones the remote directly
+func (m *Manager) clone() error {
+	cache, err := OpenCache(m.RepoURL)
+	if err == nil {
+		mirror := cache.MirrorDir()
+		if err = m.updateMirror(mirror); err == nil {
+			// A local clone hard-links the mirror's objects; pushes then go
+			// to the vault itself
+			if err = m.quietRun(m.WorkDir, "git", "clone", mirror, "repo"); err == nil {
+				return m.quietRun(filepath.Join(m.WorkDir, "repo"), "git", "remote", "set-url", "origin", m.RepoURL)
+			}
+			os.RemoveAll(filepath.Join(m.WorkDir, "repo"))
+		}
+		if m.Verbose {
+			fmt.Printf("⚠️  Could not use the cached mirror (%v), cloning in full...\n", err)
+		}
+		os.RemoveAll(mirror)
+	}
+
+	return m.quietRun(m.WorkDir, "git", "clone", m.RepoURL, "repo")
+}
+
+// updateMirror fetches the vault's branches and tags into a bare mirror,
+// creating it on first use
+func (m *Manager) updateMirror(mirror string) error {
+	if _, err := os.Stat(mirror); err != nil {
+		if err := os.MkdirAll(filepath.Dir(mirror), 0o700); err != nil {
+			return err
+		}
+		return m.quietRun(filepath.Dir(mirror), "git", "clone", "--bare", m.RepoURL, filepath.Base(mirror))
+	}
+	return m.quietRun(mirror, "git", "fetch", "--prune", "origin",
+		"+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*")
+}
+
+// cacheSnapshot stores the checked out vault in the local cache
+func (m *Manager) cacheSnapshot() error {
+	head, err := m.Head()
+	if err != nil {
+		return err
+	}
+	cache, err := OpenCache(m.RepoURL)
+	if err != nil {
+		return err
+	}
+	return cache.Store(head, filepath.Join(m.WorkDir, "repo"))
+}
+
 func (m *Manager) CommitAndPush(fileName, msg string, createPR bool) error {
 	repoDir := filepath.Join(m.WorkDir, "repo")
+	branch := "yoink-update-" + time.Now().Format("20060102150405")
+
+	base := m.DefaultBranch()
 
 	if createPR {
 		// Create branch first, before making changes
-		branch := "yoink-update-" + time.Now().Format("20060102150405")
 
-		// Make sure we're on main first
-		m.quietRun(repoDir, "git", "checkout", "main")
-		m.quietRun(repoDir, "git", "checkout", "-b", "main")
+		// Make sure we're on the default branch first
+		m.quietRun(repoDir, "git", "checkout", base)
+		m.quietRun(repoDir, "git", "checkout", "-b", base)
 
 		// Create and checkout new branch
 		if err := m.quietRun(repoDir, "git", "checkout", "-b", branch); err != nil {
@@ -124,55 +187,189 @@ func (m *Manager) CommitAndPush(fileName, msg string, createPR bool) error {
 	}
 
 	if createPR {
-		branch := "yoink-update-" + time.Now().Format("20060102150405")
+		forge, err := git.ForgeFor(m.RepoURL)
+		if err != nil {
+			return err
+		}
 
-		// Push the branch
 		if m.Verbose {
-			fmt.Printf("📤 Pushing branch %s...\n", branch)
+			fmt.Printf("📤 Pushing branch %s and opening a %s pull request...\n", branch, forge.Name())
 		}
 
-		if err := m.quietRun(repoDir, "git", "push", "origin", branch); err != nil {
-			return fmt.Errorf("failed to push branch: %w", err)
+		url, err := forge.Propose(repoDir, git.PullRequest{
+			Branch: branch,
+			Base:   base,
+			Title:  fmt.Sprintf("chore(secrets): %s", msg),
+			Body:   fmt.Sprintf("Automated secret update from Yoink.\n\n_Commit message:_ %s", msg),
+		})
+		if err != nil {
+			return err
 		}
 
-		// Create PR
+		switch {
+		case forge.Name() == git.ForgePlain:
+			fmt.Printf("🔀 Pushed branch %s - merge it into %s to apply the change\n", branch, base)
+		case url != "":
+			fmt.Printf("✅ Pull request created: %s\n", url)
+		default:
+			fmt.Printf("✅ Pull request created successfully\n")
+		}
+	} else {
+		// Direct push to the default branch
 		if m.Verbose {
-			fmt.Println("🔗 Creating pull request...")
+			fmt.Printf("📤 Pushing to %s...\n", base)
 		}
+		if err := m.quietRun(repoDir, "git", "push"); err != nil {
+			return fmt.Errorf("failed to push: %w", err)
+		}
+	}
 
-		title := fmt.Sprintf("chore(secrets): %s", msg)
-		body := fmt.Sprintf("Automated secret update from Yoink.\n\n_Commit message:_ %s", msg)
+	return nil
+}
+
+// ShortSHA abbreviates a commit SHA for display
+func ShortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+// Head returns the commit SHA checked out in the vault working copy
+func (m *Manager) Head() (string, error) {
+	out, err := exec.Command("git", "-C", filepath.Join(m.WorkDir, "repo"), "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read vault commit: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
 
-		cmd := exec.Command("gh", "pr", "create",
-			"--title", title,
-			"--body", body,
-			"--head", branch,
-			"--base", "main")
-		cmd.Dir = repoDir
+// DefaultBranch returns the vault's default branch, as recorded by the clone
+func (m *Manager) DefaultBranch() string {
+	out, err := exec.Command("git", "-C", filepath.Join(m.WorkDir, "repo"), "symbolic-ref", "--short", "refs/remotes/origin/HEAD").Output()
+	if err != nil {
+		return "main"
+	}
+	return strings.TrimPrefix(strings.TrimSpace(string(out)), "origin/")
+}
 
-		if !m.Verbose {
-			cmd.Stdout = nil
-			cmd.Stderr = nil
+// Checkout switches the vault working copy to a branch, tag or commit
+func (m *Manager) Checkout(ref string) error {
+	repoDir := filepath.Join(m.WorkDir, "repo")
+	// Branches other than the default one only exist as origin/<branch>
+	commit := ""
+	for _, name := range []string{ref, "origin/" + ref} {
+		out, err := exec.Command("git", "-C", repoDir, "rev-parse", "--verify", "--quiet", name+"^{commit}").Output()
+		if err == nil {
+			commit = strings.TrimSpace(string(out))
+			break
 		}
+	}
+	if commit == "" {
+		return fmt.Errorf("branch, tag or commit %s not found in vault", ref)
+	}
+	if output, err := exec.Command("git", "-C", repoDir, "checkout", "-q", "--detach", commit).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to check out %s: %s", ref, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// CommitBefore returns the last commit on the checked out branch made at or
+// before t
+func (m *Manager) CommitBefore(t time.Time) (string, error) {
+	out, err := exec.Command("git", "-C", filepath.Join(m.WorkDir, "repo"), "rev-list", "-1", "--first-parent",
+		"--before="+t.Format(time.RFC3339), "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read vault history: %w", err)
+	}
+	commit := strings.TrimSpace(string(out))
+	if commit == "" {
+		return "", fmt.Errorf("the vault has no commits before %s", t.Format("2006-01-02 15:04"))
+	}
+	return commit, nil
+}
+
+// Revision is a commit that touched a vault file
+type Revision struct {
+	Commit  string
+	Author  string
+	Date    time.Time
+	Subject string
+}
 
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("failed to create PR: %w", err)
+// FileHistory lists the commits on the checked out branch that touched
+// file, newest first. Only first parents are followed, so a merged pull
+// request shows up as its merge commit rather than as the commits of its
+// branch
+func (m *Manager) FileHistory(file string) ([]Revision, error) {
+	out, err := exec.Command("git", "-C", filepath.Join(m.WorkDir, "repo"), "log", "--first-parent",
+		"--format=%H%x1f%an%x1f%aI%x1f%s", "--", file).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault history: %w", err)
+	}
+
+	var revs []Revision
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.SplitN(line, "\x1f", 4)
+		if len(fields) != 4 {
+			continue
 		}
+		date, _ := time.Parse(time.RFC3339, fields[2])
+		revs = append(revs, Revision{Commit: fields[0], Author: fields[1], Date: date, Subject: fields[3]})
+	}
+	return revs, nil
+}
 
-		fmt.Printf("✅ Pull request created successfully\n")
-	} else {
-		// Direct push to main
-		if m.Verbose {
-			fmt.Println("📤 Pushing to main...")
+// FileAt returns a vault file as it was at a commit, or fs.ErrNotExist when
+// it didn't exist then
+func (m *Manager) FileAt(commit, file string) ([]byte, error) {
+	repoDir := filepath.Join(m.WorkDir, "repo")
+	if err := exec.Command("git", "-C", repoDir, "cat-file", "-e", commit+":"+file).Run(); err != nil {
+		return nil, fs.ErrNotExist
+	}
+	out, err := exec.Command("git", "-C", repoDir, "show", commit+":"+file).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s at %s: %w", file, commit, err)
+	}
+	return out, nil
+}
+
+// RemoteHead returns the commit a branch or tag of the vault repository
+// points at without cloning it; an empty ref means the default branch
+func RemoteHead(repoURL, ref string) (string, error) {
+	patterns, wants := []string{"HEAD"}, []string{"HEAD"}
+	if ref != "" {
+		// ls-remote matches any ref ending in a pattern, so pick the
+		// branch, then the commit an annotated tag points at, then the tag
+		patterns = []string{ref, ref + "^{}"}
+		wants = []string{"refs/heads/" + ref, "refs/tags/" + ref + "^{}", "refs/tags/" + ref}
+	}
+
+	out, err := exec.Command("git", append([]string{"ls-remote", repoURL}, patterns...)...).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to query vault commit: %w", err)
+	}
+
+	refs := make(map[string]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			refs[fields[1]] = fields[0]
 		}
-		if err := m.quietRun(repoDir, "git", "push"); err != nil {
-			return fmt.Errorf("failed to push: %w", err)
+	}
+	for _, w := range wants {
+		if sha, ok := refs[w]; ok {
+			return sha, nil
 		}
 	}
 
-	return nil
+	if ref == "" {
+		return "", fmt.Errorf("%s has no default branch", repoURL)
+	}
+	return "", fmt.Errorf("branch or tag %s not found in %s", ref, repoURL)
 }
 
+// Cleanup removes the working copy. The mirror in the cache is kept, so the
+// next Sync only fetches what changed
 func (m *Manager) Cleanup() {
 	if util.FileExists(m.WorkDir) {
 		os.RemoveAll(m.WorkDir)
@@ -0,0 +1,144 @@
+package vault
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runGit runs a git command in dir and returns its trimmed output
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@example.com",
+		"GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@example.com")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// newRemote creates a bare vault repository and a clone to commit to it from
+func newRemote(t *testing.T) (remote, seed string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, ".cache"))
+
+	dir := t.TempDir()
+	remote, seed = filepath.Join(dir, "vault.git"), filepath.Join(dir, "seed")
+	runGit(t, dir, "init", "-q", "--bare", "-b", "main", remote)
+	runGit(t, dir, "clone", "-q", remote, seed)
+	return remote, seed
+}
+
+// commitFile commits a file in the seed clone and pushes it
+func commitFile(t *testing.T, seed, file, content string) string {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(seed, file), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, seed, "add", file)
+	runGit(t, seed, "commit", "-q", "-m", "update "+file)
+	runGit(t, seed, "push", "-q", "origin", "HEAD:main")
+	return runGit(t, seed, "rev-parse", "HEAD")
+}
+
+func TestSyncReusesMirror(t *testing.T) {
+	remote, seed := newRemote(t)
+	commitFile(t, seed, "secrets.enc.yaml", "A: one\n")
+
+	m, err := New(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Sync(); err != nil {
+		t.Fatalf("first Sync: %v", err)
+	}
+	m.Cleanup()
+
+	cache, err := OpenCache(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A full clone would replace the mirror, and this file with it
+	marker := filepath.Join(cache.MirrorDir(), "yoink-test-marker")
+	if err := os.WriteFile(marker, nil, 0o600); err != nil {
+		t.Fatalf("mirror missing after the first sync: %v", err)
+	}
+
+	head := commitFile(t, seed, "secrets.enc.yaml", "A: two\n")
+
+	m, err = New(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Sync(); err != nil {
+		t.Fatalf("second Sync: %v", err)
+	}
+	defer m.Cleanup()
+
+	if _, err := os.Stat(marker); err != nil {
+		t.Fatalf("mirror was cloned again: %v", err)
+	}
+	if got, _ := m.Head(); got != head {
+		t.Errorf("Head = %s, want the new commit %s", got, head)
+	}
+	repoDir := filepath.Join(m.WorkDir, "repo")
+	if got := runGit(t, repoDir, "remote", "get-url", "origin"); got != remote {
+		t.Errorf("origin = %s, want pushes to go to the vault at %s", got, remote)
+	}
+	if got := m.DefaultBranch(); got != "main" {
+		t.Errorf("DefaultBranch = %s, want main", got)
+	}
+	if snaps, _ := cache.Snapshots(); len(snaps) != 2 {
+		t.Errorf("cache lists %d snapshots, want the 2 synced commits and not the mirror", len(snaps))
+	}
+}
+
+func TestFileHistoryFollowsFirstParents(t *testing.T) {
+	remote, seed := newRemote(t)
+	first := commitFile(t, seed, "secrets.enc.yaml", "A: one\n")
+
+	// A branch that changes the file and changes it back never changed it
+	// on main
+	runGit(t, seed, "checkout", "-q", "-b", "undo")
+	commitFile(t, seed, "secrets.enc.yaml", "A: two\n")
+	commitFile(t, seed, "secrets.enc.yaml", "A: one\n")
+	runGit(t, seed, "checkout", "-q", "main")
+	runGit(t, seed, "merge", "-q", "--no-ff", "-m", "merge undo", "undo")
+
+	// A branch that does change it shows up as its merge
+	runGit(t, seed, "checkout", "-q", "-b", "change")
+	commitFile(t, seed, "secrets.enc.yaml", "A: three\n")
+	runGit(t, seed, "checkout", "-q", "main")
+	runGit(t, seed, "merge", "-q", "--no-ff", "-m", "merge change", "change")
+	runGit(t, seed, "push", "-q", "origin", "main")
+	merge := runGit(t, seed, "rev-parse", "HEAD")
+
+	m, err := New(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Cleanup()
+
+	revs, err := m.FileHistory("secrets.enc.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range revs {
+		got = append(got, r.Subject)
+	}
+	if len(revs) != 2 || revs[0].Commit != merge || revs[1].Commit != first {
+		t.Fatalf("history = %q, want the change's merge and the first commit", got)
+	}
+}